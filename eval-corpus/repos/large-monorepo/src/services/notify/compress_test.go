@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPTransportCompression(t *testing.T) {
+	large := "<html>" + strings.Repeat("<p>quarterly report</p>", 100) + "</html>"
+	for _, tt := range []struct {
+		name         string
+		compress     bool
+		minBytes     int
+		message      string
+		wantEncoding string
+	}{
+		{"large body", true, 0, large, "gzip"},
+		{"below default threshold", true, 0, "disk full", ""},
+		{"custom threshold", true, 10, "disk full on db1", "gzip"},
+		{"compression off", false, 0, large, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var encoding string
+			var raw []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				encoding = r.Header.Get("Content-Encoding")
+				raw, _ = io.ReadAll(r.Body)
+			}))
+			defer srv.Close()
+			s := &Sender{Endpoint: srv.URL, Compress: tt.compress, CompressMinBytes: tt.minBytes}
+			if err := s.Send(tt.message); err != nil {
+				t.Fatal(err)
+			}
+			if encoding != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", encoding, tt.wantEncoding)
+			}
+			body := raw
+			if encoding == "gzip" {
+				zr, err := gzip.NewReader(bytes.NewReader(raw))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if body, err = io.ReadAll(zr); err != nil {
+					t.Fatal(err)
+				}
+				if tt.message == large && len(raw) >= len(body) {
+					t.Fatalf("compressed body is %d bytes, not smaller than %d", len(raw), len(body))
+				}
+			}
+			var got Notification
+			if err := json.Unmarshal(body, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Body != tt.message {
+				t.Fatalf("endpoint decoded body %q, want the original message", got.Body)
+			}
+		})
+	}
+}
+
+func TestCompressedSendRejectsEmptyMessage(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { requests++ }))
+	defer srv.Close()
+	s := &Sender{Endpoint: srv.URL, Compress: true, CompressMinBytes: 1}
+	if err := s.Send(""); err == nil || err.Error() != "empty message" {
+		t.Fatalf("Send = %v, want empty message", err)
+	}
+	if requests != 0 {
+		t.Fatalf("endpoint got %d requests, want none", requests)
+	}
+}
@@ -0,0 +1,45 @@
+package notify
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrDeduped is returned when a notification is suppressed because an
+// identical one was sent within Sender.DedupeWindow.
+var ErrDeduped = errors.New("duplicate notification suppressed")
+
+// dedupeCache remembers when each message was last sent.
+type dedupeCache struct {
+	mu   sync.Mutex
+	sent map[string]time.Time
+}
+
+// claim reports whether body may be sent, recording it as sent now if so.
+// Entries older than window are evicted on the way.
+func (c *dedupeCache) claim(body string, window time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for key, at := range c.sent {
+		if now.Sub(at) >= window {
+			delete(c.sent, key)
+		}
+	}
+	if _, ok := c.sent[body]; ok {
+		return false
+	}
+	if c.sent == nil {
+		c.sent = make(map[string]time.Time)
+	}
+	c.sent[body] = now
+	return true
+}
+
+// release forgets a claim whose send failed, so a retry is not suppressed.
+func (c *dedupeCache) release(body string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.sent, body)
+}
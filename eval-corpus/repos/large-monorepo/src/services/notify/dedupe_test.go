@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDedupeWindow(t *testing.T) {
+	tr := &fakeTransport{}
+	s := &Sender{Endpoint: "primary", Transport: tr, DedupeWindow: 50 * time.Millisecond}
+	for _, tt := range []struct {
+		name    string
+		wait    time.Duration
+		message string
+		wantErr error
+	}{
+		{"first send", 0, "disk full", nil},
+		{"duplicate within the window", 0, "disk full", ErrDeduped},
+		{"different message", 0, "disk ok", nil},
+		{"duplicate after the window", 60 * time.Millisecond, "disk full", nil},
+	} {
+		time.Sleep(tt.wait)
+		if err := s.Send(tt.message); !errors.Is(err, tt.wantErr) {
+			t.Fatalf("%s: Send = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+	if got, want := tr.bodies(), []string{"disk full", "disk ok", "disk full"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("delivered %q, want %q", got, want)
+	}
+}
+
+func TestDedupeForgetsFailedSends(t *testing.T) {
+	tr := &fakeTransport{failFirst: 1}
+	s := &Sender{Endpoint: "primary", Transport: tr, DedupeWindow: time.Minute}
+	if err := s.Send("disk full"); err == nil {
+		t.Fatal("first Send succeeded, want the transport failure")
+	}
+	if err := s.Send("disk full"); err != nil {
+		t.Fatalf("retry after a failed send = %v, want it delivered", err)
+	}
+}
+
+func TestDedupeConcurrentSends(t *testing.T) {
+	tr := &fakeTransport{}
+	s := &Sender{Endpoint: "primary", Transport: tr, DedupeWindow: time.Minute}
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Send("disk full")
+		}()
+	}
+	wg.Wait()
+	if len(tr.calls) != 1 {
+		t.Fatalf("made %d deliveries of one message, want 1", len(tr.calls))
+	}
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Channel is one named destination of a MultiSender.
+type Channel struct {
+	Name   string
+	Sender *Sender
+}
+
+// MultiSender fans a notification out to every channel at once.
+type MultiSender struct {
+	Channels []Channel
+	// FailFast cancels the channels still sending once one fails; they are
+	// then reported as failed with the context error.
+	FailFast bool
+}
+
+// MultiSendError reports a fan-out where some channels failed, naming them
+// in channel order alongside those that succeeded.
+type MultiSendError struct {
+	Succeeded []string
+	Failed    []string
+	// Err joins each failure as "name: err".
+	Err error
+}
+
+func (m *MultiSendError) Error() string {
+	total := len(m.Succeeded) + len(m.Failed)
+	succeeded := "none"
+	if len(m.Succeeded) > 0 {
+		succeeded = strings.Join(m.Succeeded, ", ")
+	}
+	return fmt.Sprintf("%d of %d channels failed (succeeded: %s): %v", len(m.Failed), total, succeeded, m.Err)
+}
+
+func (m *MultiSendError) Unwrap() error { return m.Err }
+
+func (m MultiSender) Send(message string) error {
+	return m.SendContext(context.Background(), message)
+}
+
+// SendContext sends message on every channel concurrently and waits for all
+// of them. Any failure is returned as a *MultiSendError.
+func (m MultiSender) SendContext(ctx context.Context, message string) error {
+	if len(m.Channels) == 0 {
+		return errors.New("no channels")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make([]error, len(m.Channels))
+	var wg sync.WaitGroup
+	for i, channel := range m.Channels {
+		wg.Add(1)
+		go func(i int, s *Sender) {
+			defer wg.Done()
+			if errs[i] = s.SendContext(ctx, message); errs[i] != nil && m.FailFast {
+				cancel()
+			}
+		}(i, channel.Sender)
+	}
+	wg.Wait()
+	result := &MultiSendError{}
+	var failures []error
+	for i, channel := range m.Channels {
+		if errs[i] == nil {
+			result.Succeeded = append(result.Succeeded, channel.Name)
+			continue
+		}
+		result.Failed = append(result.Failed, channel.Name)
+		failures = append(failures, fmt.Errorf("%s: %w", channel.Name, errs[i]))
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	result.Err = errors.Join(failures...)
+	return result
+}
@@ -0,0 +1,95 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowTransport delivers after delay unless ctx is done first.
+type slowTransport struct{ delay time.Duration }
+
+func (s slowTransport) Deliver(ctx context.Context, endpoint string, n Notification) error {
+	select {
+	case <-time.After(s.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestMultiSender(t *testing.T) {
+	down := &Sender{Endpoint: "email", Transport: &fakeTransport{down: map[string]bool{"email": true}}}
+	for _, tt := range []struct {
+		name          string
+		failFast      bool
+		channels      []Channel
+		wantSucceeded []string
+		wantFailed    []string
+		wantCancelled bool
+	}{
+		{
+			name: "all succeed",
+			channels: []Channel{
+				{"slack", &Sender{Endpoint: "slack", Transport: &fakeTransport{}}},
+				{"webhook", &Sender{Endpoint: "webhook", Transport: &fakeTransport{}}},
+			},
+		},
+		{
+			name: "partial failure attempts every channel",
+			channels: []Channel{
+				{"slack", &Sender{Endpoint: "slack", Transport: slowTransport{20 * time.Millisecond}}},
+				{"email", down},
+				{"webhook", &Sender{Endpoint: "webhook", Transport: &fakeTransport{}}},
+			},
+			wantSucceeded: []string{"slack", "webhook"},
+			wantFailed:    []string{"email"},
+		},
+		{
+			name:     "fail fast cancels the rest",
+			failFast: true,
+			channels: []Channel{
+				{"slack", &Sender{Endpoint: "slack", Transport: slowTransport{time.Minute}}},
+				{"email", down},
+			},
+			wantFailed:    []string{"slack", "email"},
+			wantCancelled: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			err := MultiSender{Channels: tt.channels, FailFast: tt.failFast}.Send("deploy finished")
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Fatalf("Send took %s", elapsed)
+			}
+			if tt.wantFailed == nil {
+				if err != nil {
+					t.Fatalf("Send = %v, want success", err)
+				}
+				return
+			}
+			var multiErr *MultiSendError
+			if !errors.As(err, &multiErr) {
+				t.Fatalf("Send = %v, want a *MultiSendError", err)
+			}
+			if !reflect.DeepEqual(multiErr.Succeeded, tt.wantSucceeded) || !reflect.DeepEqual(multiErr.Failed, tt.wantFailed) {
+				t.Fatalf("succeeded %v, failed %v; want %v and %v", multiErr.Succeeded, multiErr.Failed, tt.wantSucceeded, tt.wantFailed)
+			}
+			if errors.Is(err, context.Canceled) != tt.wantCancelled {
+				t.Fatalf("errors.Is(%v, context.Canceled) = %v, want %v", err, !tt.wantCancelled, tt.wantCancelled)
+			}
+			if !strings.Contains(err.Error(), "email: ") {
+				t.Fatalf("Send error %q does not name the failed channel", err)
+			}
+		})
+	}
+}
+
+func TestMultiSenderWithoutChannels(t *testing.T) {
+	if err := (MultiSender{}).Send("hello"); err == nil {
+		t.Fatal("Send with no channels succeeded")
+	}
+}
@@ -0,0 +1,16 @@
+package notify
+
+// Priority levels for Notification; higher values are more urgent.
+const (
+	PriorityLow    = -1
+	PriorityNormal = 0
+	PriorityHigh   = 1
+)
+
+// Notification is a message plus the routing details the endpoint uses to
+// deliver it.
+type Notification struct {
+	Body     string            `json:"body"`
+	Priority int               `json:"priority"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestPriorityOrdering(t *testing.T) {
+	if !(PriorityLow < PriorityNormal && PriorityNormal < PriorityHigh) {
+		t.Fatalf("priorities %d, %d, %d are not increasing in urgency", PriorityLow, PriorityNormal, PriorityHigh)
+	}
+}
+
+func TestSendNotificationPassesThrough(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		send func(s *Sender) error
+		want Notification
+	}{
+		{
+			"Send uses normal priority",
+			func(s *Sender) error { return s.Send("hello") },
+			Notification{Body: "hello", Priority: PriorityNormal},
+		},
+		{
+			"priority and metadata",
+			func(s *Sender) error {
+				return s.SendNotification(Notification{Body: "disk full", Priority: PriorityHigh, Metadata: map[string]string{"host": "db1", "team": "storage"}})
+			},
+			Notification{Body: "disk full", Priority: PriorityHigh, Metadata: map[string]string{"host": "db1", "team": "storage"}},
+		},
+		{
+			"low priority",
+			func(s *Sender) error { return s.SendNotification(Notification{Body: "fyi", Priority: PriorityLow}) },
+			Notification{Body: "fyi", Priority: PriorityLow},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &fakeTransport{}
+			if err := tt.send(&Sender{Endpoint: "primary", Transport: tr}); err != nil {
+				t.Fatal(err)
+			}
+			if len(tr.delivered) != 1 || !reflect.DeepEqual(tr.delivered[0], tt.want) {
+				t.Fatalf("delivered %+v, want %+v", tr.delivered, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendNotificationRejectsEmptyBody(t *testing.T) {
+	tr := &fakeTransport{}
+	s := &Sender{Endpoint: "primary", Transport: tr}
+	err := s.SendNotification(Notification{Priority: PriorityHigh, Metadata: map[string]string{"k": "v"}})
+	if err == nil || err.Error() != "empty message" {
+		t.Fatalf("SendNotification = %v, want empty message", err)
+	}
+	if len(tr.calls) != 0 {
+		t.Fatalf("delivered %d times, want none", len(tr.calls))
+	}
+}
+
+func TestHTTPTransportSendsNotificationDocument(t *testing.T) {
+	var got Notification
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+	want := Notification{Body: "disk full", Priority: PriorityHigh, Metadata: map[string]string{"host": "db1"}}
+	if err := (&Sender{Endpoint: srv.URL}).SendNotification(want); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("endpoint received %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimitBurstThenSpacing(t *testing.T) {
+	tr := &fakeTransport{}
+	s := &Sender{Endpoint: "primary", Transport: tr, RateLimit: 20, Burst: 3}
+	var gaps []time.Duration
+	last := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := s.Send("hello"); err != nil {
+			t.Fatal(err)
+		}
+		now := time.Now()
+		gaps = append(gaps, now.Sub(last))
+		last = now
+	}
+	// The burst goes out back to back; after it each send waits 1/20s.
+	for i, gap := range gaps {
+		if i < 3 && gap > 20*time.Millisecond {
+			t.Fatalf("send %d of the burst waited %s", i+1, gap)
+		}
+		if i >= 3 && (gap < 40*time.Millisecond || gap > 150*time.Millisecond) {
+			t.Fatalf("send %d after the burst waited %s, want about 50ms", i+1, gap)
+		}
+	}
+	if len(tr.calls) != 6 {
+		t.Fatalf("made %d deliveries, want 6", len(tr.calls))
+	}
+}
+
+func TestRateLimitUnlimited(t *testing.T) {
+	s := &Sender{Endpoint: "primary", Transport: &fakeTransport{}}
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		if err := s.Send("hello"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("100 unlimited sends took %s", elapsed)
+	}
+}
+
+func TestRateLimitWaitIsCancellable(t *testing.T) {
+	tr := &fakeTransport{}
+	s := &Sender{Endpoint: "primary", Transport: tr, RateLimit: 0.5}
+	if err := s.Send("hello"); err != nil {
+		t.Fatal(err)
+	}
+	// The next token is two seconds away.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := s.SendContext(ctx, "hello"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("SendContext = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("cancelled wait took %s", elapsed)
+	}
+	if len(tr.calls) != 1 {
+		t.Fatalf("made %d deliveries, want only the first", len(tr.calls))
+	}
+}
@@ -1,21 +1,215 @@
 package notify
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"text/template"
+	"time"
+)
 
-// Sender delivers notifications to an external endpoint.
+// Sender delivers notifications to an external endpoint. A Sender must not
+// be copied after first use.
 type Sender struct {
 	Endpoint   string
 	RetryLimit int
+	// Endpoints are failover targets tried in order after Endpoint.
+	Endpoints []string
+	// Template is a text/template source rendered by SendTemplate.
+	Template string
+	// Transport delivers to each endpoint; nil means an HTTPTransport with
+	// the Compress settings below.
+	Transport Transport
+	// Compress gzips request bodies of at least CompressMinBytes (1 KiB
+	// when zero) on the default transport.
+	Compress         bool
+	CompressMinBytes int
+	// RateLimit caps sends per second, allowing Burst (at least 1) back to
+	// back after an idle period. Sends over the limit block until a token
+	// is free. Zero means unlimited.
+	RateLimit float64
+	Burst     int
+	// DedupeWindow suppresses a notification with ErrDeduped when one with
+	// the same body was sent within the window. Zero disables dedupe.
+	DedupeWindow time.Duration
+	// BaseDelay is the wait after the first failed pass over the endpoints,
+	// 50ms when zero. It doubles per pass up to MaxDelay (zero means
+	// uncapped), and each wait is jittered to between half and all of it.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	limiter tokenBucket
+	dedupe  dedupeCache
+}
+
+func (s *Sender) Send(message string) error {
+	return s.SendContext(context.Background(), message)
+}
+
+// SendContext sends message as a Notification with PriorityNormal.
+func (s *Sender) SendContext(ctx context.Context, message string) error {
+	return s.SendNotificationContext(ctx, Notification{Body: message, Priority: PriorityNormal})
+}
+
+func (s *Sender) SendNotification(n Notification) error {
+	return s.SendNotificationContext(context.Background(), n)
+}
+
+// SendNotificationContext delivers n, trying each endpoint in order until
+// one accepts it. A full pass over the endpoints is repeated up to
+// RetryLimit times with a jittered exponential backoff between passes. A
+// done ctx aborts the loop, and a delay in progress returns ctx.Err().
+func (s *Sender) SendNotificationContext(ctx context.Context, n Notification) error {
+	endpoints := s.endpoints()
+	if len(endpoints) == 0 {
+		return errors.New("missing endpoint")
+	}
+	if n.Body == "" {
+		return errors.New("empty message")
+	}
+	if s.DedupeWindow > 0 {
+		if !s.dedupe.claim(n.Body, s.DedupeWindow) {
+			return ErrDeduped
+		}
+		err := s.deliver(ctx, n)
+		if err != nil {
+			s.dedupe.release(n.Body)
+		}
+		return err
+	}
+	return s.deliver(ctx, n)
+}
+
+// deliver rate limits n and runs the failover and retry loop.
+func (s *Sender) deliver(ctx context.Context, n Notification) error {
+	if err := s.limiter.wait(ctx, s.RateLimit, s.Burst); err != nil {
+		return fmt.Errorf("send aborted waiting for rate limit: %w", err)
+	}
+	endpoints := s.endpoints()
+	passes := s.RetryLimit
+	if passes < 1 {
+		passes = 1
+	}
+	transport := s.transport()
+	failures := make([]error, len(endpoints))
+	for pass := 1; pass <= passes; pass++ {
+		for i, endpoint := range endpoints {
+			if failures[i] = transport.Deliver(ctx, endpoint, n); failures[i] == nil {
+				return nil
+			}
+		}
+		if pass == passes {
+			break
+		}
+		if err := sleep(ctx, s.backoff(pass)); err != nil {
+			return err
+		}
+	}
+	if len(endpoints) == 1 {
+		return fmt.Errorf("send failed after %d attempts: %w", passes, failures[0])
+	}
+	for i, endpoint := range endpoints {
+		failures[i] = fmt.Errorf("%s: %w", endpoint, failures[i])
+	}
+	return fmt.Errorf("send failed on all %d endpoints after %d passes: %w", len(endpoints), passes, errors.Join(failures...))
 }
 
-func (s Sender) Send(message string) error {
-	if s.Endpoint == "" {
+// Ping checks that at least one endpoint is reachable, trying them in
+// failover order, without sending a notification. Transports that do not
+// implement Pinger are assumed reachable.
+func (s *Sender) Ping(ctx context.Context) error {
+	endpoints := s.endpoints()
+	if len(endpoints) == 0 {
 		return errors.New("missing endpoint")
 	}
-	for attempt := 1; attempt <= s.RetryLimit; attempt++ {
-		if message != "" {
+	pinger, ok := s.transport().(Pinger)
+	if !ok {
+		return nil
+	}
+	failures := make([]error, len(endpoints))
+	for i, endpoint := range endpoints {
+		if failures[i] = pinger.Ping(ctx, endpoint); failures[i] == nil {
 			return nil
 		}
 	}
-	return errors.New("empty message")
+	if len(endpoints) == 1 {
+		return fmt.Errorf("ping failed: %w", failures[0])
+	}
+	for i, endpoint := range endpoints {
+		failures[i] = fmt.Errorf("%s: %w", endpoint, failures[i])
+	}
+	return fmt.Errorf("ping failed on all %d endpoints: %w", len(endpoints), errors.Join(failures...))
+}
+
+// endpoints lists delivery targets in failover order: Endpoint, if set,
+// followed by Endpoints.
+func (s *Sender) endpoints() []string {
+	var endpoints []string
+	if s.Endpoint != "" {
+		endpoints = append(endpoints, s.Endpoint)
+	}
+	for _, endpoint := range s.Endpoints {
+		if endpoint != "" {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+func (s *Sender) transport() Transport {
+	if s.Transport != nil {
+		return s.Transport
+	}
+	return HTTPTransport{Compress: s.Compress, CompressMinBytes: s.CompressMinBytes}
+}
+
+func (s *Sender) backoff(pass int) time.Duration {
+	d := s.BaseDelay
+	if d <= 0 {
+		d = 50 * time.Millisecond
+	}
+	for i := 1; i < pass; i++ {
+		if s.MaxDelay > 0 && d >= s.MaxDelay {
+			break
+		}
+		if d > math.MaxInt64/2 {
+			d = math.MaxInt64
+			break
+		}
+		d *= 2
+	}
+	if s.MaxDelay > 0 && d > s.MaxDelay {
+		d = s.MaxDelay
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// SendTemplate renders Template against data and sends the result. Nothing
+// is sent if the template fails to parse or execute, including when it
+// references a missing map key.
+func (s *Sender) SendTemplate(data interface{}) error {
+	tmpl, err := template.New("notification").Option("missingkey=error").Parse(s.Template)
+	if err != nil {
+		return fmt.Errorf("parse template: %w", err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return fmt.Errorf("render template: %w", err)
+	}
+	return s.Send(b.String())
 }
@@ -0,0 +1,184 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTransport records every delivery. It fails the first failFirst calls
+// and every call to an endpoint in down.
+type fakeTransport struct {
+	mu        sync.Mutex
+	failFirst int
+	down      map[string]bool
+	// calls lists the endpoint of each call, in order.
+	calls     []string
+	delivered []Notification
+}
+
+func (f *fakeTransport) Deliver(ctx context.Context, endpoint string, n Notification) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, endpoint)
+	if len(f.calls) <= f.failFirst {
+		return errors.New("transient failure")
+	}
+	if f.down[endpoint] {
+		return errors.New(endpoint + " down")
+	}
+	f.delivered = append(f.delivered, n)
+	return nil
+}
+
+func (f *fakeTransport) bodies() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var bodies []string
+	for _, n := range f.delivered {
+		bodies = append(bodies, n.Body)
+	}
+	return bodies
+}
+
+func TestSendTemplate(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		template string
+		data     interface{}
+		want     string
+		wantErr  string
+	}{
+		{"valid template", "{{.Service}} is {{.State}}", map[string]string{"Service": "api", "State": "down"}, "api is down", ""},
+		{"struct data", "{{.Name}}!", struct{ Name string }{"deploy"}, "deploy!", ""},
+		{"missing map key", "{{.Service}} is {{.State}}", map[string]string{"Service": "api"}, "", "render template"},
+		{"missing struct field", "{{.Missing}}", struct{ Name string }{"deploy"}, "", "render template"},
+		{"parse error", "{{.Service", nil, "", "parse template"},
+		{"renders empty", "{{if .Send}}hello{{end}}", map[string]bool{"Send": false}, "", "empty message"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &fakeTransport{}
+			s := &Sender{Endpoint: "primary", Template: tt.template, Transport: tr}
+			err := s.SendTemplate(tt.data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("SendTemplate = %v, want an error containing %q", err, tt.wantErr)
+				}
+				if len(tr.calls) != 0 {
+					t.Fatalf("delivered %d times, want nothing sent", len(tr.calls))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tr.bodies(); len(got) != 1 || got[0] != tt.want {
+				t.Fatalf("delivered %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailover(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		endpoint  string
+		endpoints []string
+		down      []string
+		wantCalls []string
+		wantErr   bool
+	}{
+		{"single endpoint", "primary", nil, nil, []string{"primary"}, false},
+		{"single endpoint down", "primary", nil, []string{"primary"}, []string{"primary"}, true},
+		{"first healthy endpoint wins", "primary", []string{"backup"}, nil, []string{"primary"}, false},
+		{"fails over in order", "primary", []string{"backup1", "backup2"}, []string{"primary", "backup1"}, []string{"primary", "backup1", "backup2"}, false},
+		{"Endpoints without Endpoint", "", []string{"a", "b"}, []string{"a"}, []string{"a", "b"}, false},
+		{"empty entries skipped", "", []string{"", "a"}, nil, []string{"a"}, false},
+		{"all endpoints down", "primary", []string{"backup"}, []string{"primary", "backup"}, []string{"primary", "backup"}, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &fakeTransport{down: make(map[string]bool)}
+			for _, endpoint := range tt.down {
+				tr.down[endpoint] = true
+			}
+			s := &Sender{Endpoint: tt.endpoint, Endpoints: tt.endpoints, RetryLimit: 1, Transport: tr}
+			err := s.Send("hello")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(tr.calls, tt.wantCalls) {
+				t.Fatalf("tried %q, want %q", tr.calls, tt.wantCalls)
+			}
+			if err == nil {
+				return
+			}
+			for _, endpoint := range tt.down {
+				if !strings.Contains(err.Error(), endpoint+" down") {
+					t.Fatalf("error %q does not report %s's failure", err, endpoint)
+				}
+			}
+		})
+	}
+}
+
+func TestSendWithoutEndpoint(t *testing.T) {
+	tr := &fakeTransport{}
+	if err := (&Sender{Transport: tr}).Send("hello"); err == nil || err.Error() != "missing endpoint" {
+		t.Fatalf("Send = %v, want missing endpoint", err)
+	}
+	if len(tr.calls) != 0 {
+		t.Fatalf("delivered %d times without an endpoint", len(tr.calls))
+	}
+}
+
+func TestFailoverPasses(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		endpoints []string
+		passes    int
+		wantErr   string
+	}{
+		{"one endpoint", []string{"a"}, 3, "send failed after 3 attempts"},
+		{"every endpoint down", []string{"a", "b", "c"}, 4, "send failed on all 3 endpoints after 4 passes"},
+		{"retry limit below one", []string{"a", "b"}, 0, "send failed on all 2 endpoints"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			down := make(map[string]bool)
+			for _, endpoint := range tt.endpoints {
+				down[endpoint] = true
+			}
+			tr := &fakeTransport{down: down}
+			s := &Sender{Endpoints: tt.endpoints, RetryLimit: tt.passes, BaseDelay: time.Millisecond, Transport: tr}
+			err := s.Send("hello")
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Fatalf("Send = %v, want %q", err, tt.wantErr)
+			}
+			passes := max(tt.passes, 1)
+			if want := len(tt.endpoints) * passes; len(tr.calls) != want {
+				t.Fatalf("made %d deliveries, want %d endpoints x %d passes", len(tr.calls), len(tt.endpoints), passes)
+			}
+		})
+	}
+}
+
+func TestFailoverBackoffIsCancellable(t *testing.T) {
+	tr := &fakeTransport{down: map[string]bool{"a": true, "b": true}}
+	s := &Sender{Endpoints: []string{"a", "b"}, RetryLimit: 5, BaseDelay: time.Second, Transport: tr}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := s.SendContext(ctx, "hello"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("SendContext = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("cancelled backoff took %s", elapsed)
+	}
+	// The first pass ran; the wait before the second was cut short.
+	if len(tr.calls) != 2 {
+		t.Fatalf("made %d deliveries, want one pass over 2 endpoints", len(tr.calls))
+	}
+}
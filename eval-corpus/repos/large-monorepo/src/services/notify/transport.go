@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Transport delivers a notification to a single endpoint. Any error is
+// treated as transient and retried by Sender.
+type Transport interface {
+	Deliver(ctx context.Context, endpoint string, n Notification) error
+}
+
+// HTTPTransport POSTs notifications to the endpoint as JSON documents.
+type HTTPTransport struct {
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+	// Compress gzips documents of at least CompressMinBytes (1 KiB when
+	// zero); smaller ones are sent as is.
+	Compress         bool
+	CompressMinBytes int
+}
+
+func (t HTTPTransport) Deliver(ctx context.Context, endpoint string, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	encoding := ""
+	if t.Compress {
+		if body, encoding, err = t.compress(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+const defaultCompressMinBytes = 1024
+
+// compress gzips body when it meets the compression threshold, returning
+// the content encoding to send with it.
+func (t HTTPTransport) compress(body []byte) ([]byte, string, error) {
+	threshold := t.CompressMinBytes
+	if threshold <= 0 {
+		threshold = defaultCompressMinBytes
+	}
+	if len(body) < threshold {
+		return body, "", nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return nil, "", err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "gzip", nil
+}
+
+// Pinger is implemented by transports that can check an endpoint is
+// reachable without delivering a notification. Sender.Ping uses it.
+type Pinger interface {
+	Ping(ctx context.Context, endpoint string) error
+}
+
+// Ping sends a HEAD request to endpoint. Any response below 500 counts as
+// reachable, since endpoints commonly reject HEAD on a POST route.
+func (t HTTPTransport) Ping(ctx context.Context, endpoint string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
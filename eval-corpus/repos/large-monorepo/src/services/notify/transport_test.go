@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetriesTransportFailures(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		failFirst int
+		wantCalls int
+		wantErr   bool
+	}{
+		{"first attempt succeeds", 0, 1, false},
+		{"succeeds after one failure", 1, 2, false},
+		{"succeeds on last attempt", 4, 5, false},
+		{"every attempt fails", 5, 5, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &fakeTransport{failFirst: tt.failFirst}
+			s := &Sender{Endpoint: "primary", RetryLimit: 5, BaseDelay: time.Millisecond, Transport: tr}
+			err := s.Send("hello")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send = %v, want error %v", err, tt.wantErr)
+			}
+			if len(tr.calls) != tt.wantCalls {
+				t.Fatalf("made %d deliveries, want %d", len(tr.calls), tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDefaultHTTPTransport(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"accepted", http.StatusAccepted, false},
+		{"rejected", http.StatusBadRequest, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var method, contentType string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				method, contentType = r.Method, r.Header.Get("Content-Type")
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+			err := (&Sender{Endpoint: srv.URL, BaseDelay: time.Millisecond}).Send("hello")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send = %v, want error %v", err, tt.wantErr)
+			}
+			if method != http.MethodPost || contentType != "application/json" {
+				t.Fatalf("endpoint got %s with Content-Type %q, want a JSON POST", method, contentType)
+			}
+		})
+	}
+}
+
+func TestPing(t *testing.T) {
+	var methods []string
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	defer live.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	for _, tt := range []struct {
+		name      string
+		s         *Sender
+		wantErr   string
+		wantPings int
+	}{
+		{"live server", &Sender{Endpoint: live.URL}, "", 1},
+		{"closed server", &Sender{Endpoint: closed.URL}, "ping failed", 0},
+		{"fails over to a live endpoint", &Sender{Endpoint: closed.URL, Endpoints: []string{live.URL}}, "", 1},
+		{"every endpoint down", &Sender{Endpoints: []string{closed.URL, closed.URL}}, "ping failed on all 2 endpoints", 0},
+		{"missing endpoint", &Sender{}, "missing endpoint", 0},
+		{"transport without Ping", &Sender{Endpoint: "primary", Transport: &fakeTransport{}}, "", 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			methods = nil
+			err := tt.s.Ping(context.Background())
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Ping = %v, want success", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("Ping = %v, want error containing %q", err, tt.wantErr)
+			}
+			if len(methods) != tt.wantPings {
+				t.Fatalf("live server got %v, want %d HEAD requests", methods, tt.wantPings)
+			}
+			for _, m := range methods {
+				if m != http.MethodHead {
+					t.Fatalf("live server got %s, want HEAD", m)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by Add once the AsyncExporter has been closed or
+// drained.
+var ErrClosed = errors.New("exporter closed")
+
+// AsyncExporter buffers records added one at a time and sends them in the
+// background through an Exporter. A flush happens when Exporter.BatchSize
+// records have accumulated or the flush interval has elapsed, whichever comes
+// first.
+type AsyncExporter struct {
+	exporter *Exporter
+	onError  func(error)
+
+	mu      sync.Mutex
+	buf     []Record
+	closed  bool
+	full    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	// drainCtx bounds the final flush; it is set before done is closed.
+	drainCtx context.Context
+	// lastErr collects the errors of flushes that finish once closed is
+	// set, for Drain to return. Only run writes it.
+	lastErr error
+	// log, if set, holds every record that is buffered or in flight.
+	log *recordLog
+	// coalescer, if set, merges buffered records; index maps its keys to
+	// positions in buf.
+	coalescer *Coalescer
+	index     map[string]int
+}
+
+// NewAsyncExporter starts a background flusher for e. A non-positive
+// flushInterval disables time-based flushing. onError, which may be nil,
+// receives the errors of background flushes.
+func NewAsyncExporter(e *Exporter, flushInterval time.Duration, onError func(error)) *AsyncExporter {
+	a := newAsyncExporter(e, onError)
+	go a.run(flushInterval)
+	return a
+}
+
+// NewDurableAsyncExporter is NewAsyncExporter with the buffer backed by a
+// write-ahead log at path, so records survive a crash. Records left in the
+// log by a previous process are buffered ahead of any new ones and flushed
+// straight away. A record leaves the log once the batch carrying it is
+// delivered or it expires under MaxAge. Records that fail to deliver go back
+// to the front of the buffer, ahead of those added since, and are retried by
+// the next flush; any still undelivered when the exporter is drained stay in
+// the log and are replayed on the next start.
+func NewDurableAsyncExporter(e *Exporter, path string, flushInterval time.Duration, onError func(error)) (*AsyncExporter, error) {
+	log, replay, err := openRecordLog(path)
+	if err != nil {
+		return nil, err
+	}
+	a := newAsyncExporter(e, onError)
+	a.log = log
+	a.buf = replay
+	if len(replay) > 0 {
+		a.full <- struct{}{}
+	}
+	go a.run(flushInterval)
+	return a, nil
+}
+
+func newAsyncExporter(e *Exporter, onError func(error)) *AsyncExporter {
+	return &AsyncExporter{
+		exporter: e,
+		onError:  onError,
+		full:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Add buffers record for the next flush. With a log, the record is only
+// buffered once it has been written to the log.
+func (a *AsyncExporter) Add(record Record) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return ErrClosed
+	}
+	if a.log != nil {
+		if err := a.log.append(record); err != nil {
+			return err
+		}
+	}
+	if a.coalescer != nil {
+		a.buf = a.coalescer.add(a.buf, a.index, record)
+	} else {
+		a.buf = append(a.buf, record)
+	}
+	if a.exporter.BatchSize > 0 && len(a.buf) >= a.exporter.BatchSize {
+		select {
+		case a.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// SetCoalescer merges buffered records by c's key before each flush, so a
+// key updated several times between flushes is sent once with its latest
+// record. Records already buffered are merged straight away. A nil c stops
+// merging.
+func (a *AsyncExporter) SetCoalescer(c *Coalescer) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.coalescer = c
+	a.index = nil
+	if c == nil {
+		return
+	}
+	a.index = make(map[string]int)
+	var merged []Record
+	for _, record := range a.buf {
+		merged = c.add(merged, a.index, record)
+	}
+	a.buf = merged
+}
+
+// Close drains the exporter without a deadline.
+func (a *AsyncExporter) Close() error {
+	return a.Drain(context.Background())
+}
+
+// Drain stops accepting records, flushes whatever is buffered and waits for
+// that flush, along with any flush already in progress, to finish. It
+// returns the delivery errors of every flush that finished after it was
+// called, joined, or ctx.Err() if ctx is done first; the final flush is sent
+// under ctx and so is abandoned too.
+func (a *AsyncExporter) Drain(ctx context.Context) error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return ErrClosed
+	}
+	a.closed = true
+	a.drainCtx = ctx
+	a.mu.Unlock()
+	close(a.done)
+	select {
+	case <-a.stopped:
+		return a.lastErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (a *AsyncExporter) run(interval time.Duration) {
+	defer close(a.stopped)
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-a.full:
+		case <-tick:
+		case <-a.done:
+		}
+		// A full or tick may win the select even once done is closed; the
+		// drain's flush must still run under its ctx.
+		select {
+		case <-a.done:
+			a.drain()
+			return
+		default:
+			a.report(a.flush(context.Background()))
+		}
+	}
+}
+
+// drain runs the final flush under drainCtx and closes the log.
+func (a *AsyncExporter) drain() {
+	a.lastErr = errors.Join(a.lastErr, a.flush(a.drainCtx))
+	if a.log != nil {
+		if err := a.log.close(); err != nil {
+			a.lastErr = errors.Join(a.lastErr, err)
+		}
+	}
+}
+
+// flush sends everything buffered so far, chunked by BatchSize.
+func (a *AsyncExporter) flush(ctx context.Context) error {
+	a.mu.Lock()
+	records := a.buf
+	a.buf = nil
+	if a.coalescer != nil {
+		a.index = make(map[string]int)
+	}
+	a.mu.Unlock()
+	if len(records) == 0 {
+		return nil
+	}
+	if a.log == nil {
+		return a.exporter.SendAllContext(ctx, records)
+	}
+	results := make([]RecordResult, len(records))
+	err := a.exporter.sendAll(ctx, records, results)
+	// Put the records that were neither delivered nor expired back ahead of
+	// what has been added since, and shrink the log to match the buffer.
+	var failed []Record
+	for _, result := range results {
+		if result.Err != nil && !errors.Is(result.Err, ErrRecordExpired) {
+			failed = append(failed, result.Record)
+		}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(failed) > 0 {
+		a.requeue(failed)
+	}
+	if resetErr := a.log.reset(a.buf); resetErr != nil {
+		return errors.Join(err, resetErr)
+	}
+	return err
+}
+
+// requeue puts records back at the front of the buffer for the next flush,
+// merging them with newer records under the coalescer if one is set. The
+// caller holds a.mu.
+func (a *AsyncExporter) requeue(records []Record) {
+	buf := append(records, a.buf...)
+	if a.coalescer == nil {
+		a.buf = buf
+		return
+	}
+	a.index = make(map[string]int)
+	a.buf = nil
+	for _, record := range buf {
+		a.buf = a.coalescer.add(a.buf, a.index, record)
+	}
+}
+
+// report hands the error of a background flush to onError. A flush that
+// finishes after Drain was called is one Drain waited for, so its error is
+// also kept for Drain to return.
+func (a *AsyncExporter) report(err error) {
+	if err == nil {
+		return
+	}
+	a.mu.Lock()
+	closed := a.closed
+	a.mu.Unlock()
+	if closed {
+		a.lastErr = errors.Join(a.lastErr, err)
+	}
+	if a.onError != nil {
+		a.onError(err)
+	}
+}
@@ -0,0 +1,223 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// chanTransport sends the IDs of each delivered batch on a channel, failing
+// the delivery with err if set.
+type chanTransport struct {
+	batches chan string
+	err     error
+}
+
+func newChanTransport() *chanTransport {
+	return &chanTransport{batches: make(chan string, 16)}
+}
+
+func (c *chanTransport) Deliver(ctx context.Context, batch Batch) error {
+	var ids string
+	for _, record := range batch.Records {
+		ids += record.ID
+	}
+	c.batches <- ids
+	return c.err
+}
+
+// next waits for the next delivered batch.
+func (c *chanTransport) next(t *testing.T) string {
+	t.Helper()
+	select {
+	case ids := <-c.batches:
+		return ids
+	case <-time.After(time.Second):
+		t.Fatal("no batch delivered")
+		return ""
+	}
+}
+
+// idle checks that nothing is delivered for a short while.
+func (c *chanTransport) idle(t *testing.T) {
+	t.Helper()
+	select {
+	case ids := <-c.batches:
+		t.Fatalf("unexpected batch %q", ids)
+	case <-time.After(30 * time.Millisecond):
+	}
+}
+
+func addAll(t *testing.T, a *AsyncExporter, ids string) {
+	t.Helper()
+	for _, id := range ids {
+		if err := a.Add(Record{ID: string(id), Payload: "p"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAsyncExporterFlushesOnSize(t *testing.T) {
+	tr := newChanTransport()
+	a := NewAsyncExporter(&Exporter{Transport: tr, BatchSize: 2}, 0, nil)
+	addAll(t, a, "ab")
+	if got := tr.next(t); got != "ab" {
+		t.Fatalf("flushed %q, want ab", got)
+	}
+	addAll(t, a, "c")
+	tr.idle(t)
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := tr.next(t); got != "c" {
+		t.Fatalf("flushed %q on close, want c", got)
+	}
+}
+
+func TestAsyncExporterFlushesOnInterval(t *testing.T) {
+	tr := newChanTransport()
+	a := NewAsyncExporter(&Exporter{Transport: tr, BatchSize: 100}, 20*time.Millisecond, nil)
+	defer a.Close()
+	addAll(t, a, "a")
+	if got := tr.next(t); got != "a" {
+		t.Fatalf("flushed %q, want a", got)
+	}
+	addAll(t, a, "b")
+	if got := tr.next(t); got != "b" {
+		t.Fatalf("flushed %q, want b", got)
+	}
+}
+
+func TestAsyncExporterFlushesOnClose(t *testing.T) {
+	tr := newChanTransport()
+	a := NewAsyncExporter(&Exporter{Transport: tr, BatchSize: 100}, 0, nil)
+	addAll(t, a, "abc")
+	tr.idle(t)
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Close returns only once the final flush has been delivered.
+	select {
+	case got := <-tr.batches:
+		if got != "abc" {
+			t.Fatalf("flushed %q on close, want abc", got)
+		}
+	default:
+		t.Fatal("Close returned before flushing")
+	}
+	if err := a.Add(Record{ID: "d", Payload: "p"}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Add after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestAsyncExporterReportsBackgroundErrors(t *testing.T) {
+	tr := newChanTransport()
+	tr.err = errors.New("rejected")
+	errs := make(chan error, 1)
+	a := NewAsyncExporter(&Exporter{Transport: tr, BatchSize: 1}, 0, func(err error) { errs <- err })
+	defer a.Close()
+	addAll(t, a, "a")
+	select {
+	case err := <-errs:
+		if !errors.Is(err, tr.err) {
+			t.Fatalf("onError got %v, want %v", err, tr.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("onError not called")
+	}
+}
+
+func TestDrainDeliversEverything(t *testing.T) {
+	tr := &InMemoryTransport{}
+	a := NewAsyncExporter(&Exporter{Transport: tr, BatchSize: 3}, time.Hour, nil)
+	addAll(t, a, "abcdefghij")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := a.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := sentIDs(tr); got != "abcdefghij" {
+		t.Fatalf("delivered %q, want every record once in order", got)
+	}
+	if err := a.Add(Record{ID: "k", Payload: "p"}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Add after Drain = %v, want ErrClosed", err)
+	}
+	if err := a.Drain(ctx); !errors.Is(err, ErrClosed) {
+		t.Fatalf("second Drain = %v, want ErrClosed", err)
+	}
+}
+
+func TestDrainReturnsInFlightFlushError(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	rejected := errors.New("rejected")
+	tr := transportFunc(func(ctx context.Context, batch Batch) error {
+		if batch.Records[0].ID == "a" {
+			close(started)
+			<-release
+			return rejected
+		}
+		return nil
+	})
+	var reported []error
+	a := NewAsyncExporter(&Exporter{Transport: tr, BatchSize: 1}, 0, func(err error) { reported = append(reported, err) })
+	addAll(t, a, "a")
+	<-started
+	drained := make(chan error, 1)
+	go func() { drained <- a.Drain(context.Background()) }()
+	// Wait for Drain to close the exporter before the flush finishes.
+	for a.Add(Record{ID: "x", Payload: "p"}) == nil {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	err := <-drained
+	if !errors.Is(err, rejected) {
+		t.Fatalf("Drain = %v, want the in-flight flush's error", err)
+	}
+	if len(reported) != 1 || !errors.Is(reported[0], rejected) {
+		t.Fatalf("onError got %v, want the flush error too", reported)
+	}
+}
+
+func TestDrainDeadline(t *testing.T) {
+	tr := transportFunc(func(ctx context.Context, batch Batch) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	a := NewAsyncExporter(&Exporter{Transport: tr, BatchSize: 100}, 0, nil)
+	addAll(t, a, "ab")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := a.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Drain = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("Drain took %s past its deadline", elapsed)
+	}
+}
+
+func TestDrainFlushesUnderItsContext(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		// A full buffer signalled just before Drain must still be flushed
+		// under the drain's ctx, not in the background.
+		ctxs := make(chan context.Context, 2)
+		tr := transportFunc(func(ctx context.Context, batch Batch) error {
+			ctxs <- ctx
+			return nil
+		})
+		a := newAsyncExporter(&Exporter{Transport: tr, BatchSize: 1}, nil)
+		a.buf = []Record{{ID: "a", Payload: "p"}}
+		a.full <- struct{}{}
+		type key struct{}
+		ctx := context.WithValue(context.Background(), key{}, true)
+		a.closed = true
+		a.drainCtx = ctx
+		close(a.done)
+		a.run(0)
+		if got := <-ctxs; got.Value(key{}) == nil {
+			t.Fatal("final flush ran outside the drain's ctx")
+		}
+	}
+}
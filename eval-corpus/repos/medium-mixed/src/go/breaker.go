@@ -0,0 +1,77 @@
+package exporter
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without attempting delivery while the circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("circuit open")
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuitBreaker trips after threshold consecutive batch failures. Once
+// resetTimeout has passed it lets a single probe batch through: success
+// closes the circuit, failure reopens it.
+type circuitBreaker struct {
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+}
+
+func (b *circuitBreaker) allow(threshold int, resetTimeout time.Duration) error {
+	if threshold <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case circuitOpen:
+		if time.Since(b.openedAt) < resetTimeout {
+			return ErrCircuitOpen
+		}
+		b.state = circuitHalfOpen
+		return nil
+	case circuitHalfOpen:
+		// A probe is already in flight.
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// record reports the outcome of a batch admitted by allow. Cancelled sends
+// say nothing about the endpoint, so they release a probe without counting.
+func (b *circuitBreaker) record(threshold int, err error, cancelled bool) {
+	if threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case cancelled:
+		if b.state == circuitHalfOpen {
+			b.state = circuitOpen
+		}
+	case err == nil:
+		b.state = circuitClosed
+		b.failures = 0
+	case b.state == circuitHalfOpen:
+		b.state = circuitOpen
+		b.openedAt = time.Now()
+	default:
+		b.failures++
+		if b.failures >= threshold {
+			b.state = circuitOpen
+			b.openedAt = time.Now()
+		}
+	}
+}
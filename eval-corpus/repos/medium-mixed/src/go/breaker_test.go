@@ -0,0 +1,115 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	const threshold, reset = 2, 20 * time.Millisecond
+	failed := errors.New("failed")
+	// Steps: allow and reject expect allow to admit or refuse a batch; ok,
+	// fail and cancel record an outcome; wait sleeps past the reset timeout.
+	for _, tt := range []struct {
+		name  string
+		steps []string
+		want  circuitState
+	}{
+		{"closed below threshold", []string{"fail", "allow", "ok", "fail", "allow"}, circuitClosed},
+		{"opens at threshold", []string{"fail", "fail", "reject"}, circuitOpen},
+		{"open until reset timeout", []string{"fail", "fail", "reject", "reject"}, circuitOpen},
+		{"half-open after reset timeout", []string{"fail", "fail", "wait", "allow"}, circuitHalfOpen},
+		{"one probe at a time", []string{"fail", "fail", "wait", "allow", "reject"}, circuitHalfOpen},
+		{"probe success closes", []string{"fail", "fail", "wait", "allow", "ok", "allow", "fail", "allow"}, circuitClosed},
+		{"probe failure reopens", []string{"fail", "fail", "wait", "allow", "fail", "reject"}, circuitOpen},
+		{"cancelled probe releases without counting", []string{"fail", "fail", "wait", "allow", "cancel", "allow"}, circuitHalfOpen},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var b circuitBreaker
+			for i, step := range tt.steps {
+				switch step {
+				case "allow":
+					if err := b.allow(threshold, reset); err != nil {
+						t.Fatalf("step %d: allow = %v, want nil", i, err)
+					}
+				case "reject":
+					if err := b.allow(threshold, reset); !errors.Is(err, ErrCircuitOpen) {
+						t.Fatalf("step %d: allow = %v, want ErrCircuitOpen", i, err)
+					}
+				case "ok":
+					b.record(threshold, nil, false)
+				case "fail":
+					b.record(threshold, failed, false)
+				case "cancel":
+					b.record(threshold, context.Canceled, true)
+				case "wait":
+					time.Sleep(reset + 5*time.Millisecond)
+				}
+			}
+			if b.state != tt.want {
+				t.Fatalf("state %d, want %d", b.state, tt.want)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerDisabledByZeroThreshold(t *testing.T) {
+	var b circuitBreaker
+	for i := 0; i < 10; i++ {
+		b.record(0, errors.New("failed"), false)
+	}
+	if err := b.allow(0, time.Hour); err != nil {
+		t.Fatalf("allow = %v with the breaker disabled", err)
+	}
+}
+
+func TestSendBatchCircuitBreaker(t *testing.T) {
+	var attempts atomic.Int32
+	var down atomic.Bool
+	down.Store(true)
+	tr := transportFunc(func(ctx context.Context, batch Batch) error {
+		attempts.Add(1)
+		if down.Load() {
+			return errors.New("endpoint down")
+		}
+		return nil
+	})
+	e := &Exporter{Transport: tr, ConsecutiveFailureThreshold: 2, CircuitResetTimeout: 20 * time.Millisecond}
+	records := []Record{{ID: "a", Payload: "p"}}
+	for i := 0; i < 2; i++ {
+		if err := e.SendBatch(records); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("send %d = %v, want the transport error", i+1, err)
+		}
+	}
+	if err := e.SendBatch(records); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("SendBatch = %v with the circuit open, want ErrCircuitOpen", err)
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Fatalf("made %d attempts, want none while open", got)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	down.Store(false)
+	if err := e.SendBatch(records); err != nil {
+		t.Fatalf("probe = %v, want success", err)
+	}
+	// Closed again, concurrent sends all go through.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := e.SendBatch(records); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := attempts.Load(); got != 11 {
+		t.Fatalf("made %d attempts, want 11", got)
+	}
+}
@@ -0,0 +1,88 @@
+package exporter
+
+// Clone returns a copy of e's configuration. The circuit breaker, Stats
+// counters, rate limiter, retry budget and sequence numbers start fresh
+// rather than being shared. Transport, Encoder and hooks are shared, except
+// that an HTTPTransport is copied along with its Headers so either side
+// can change them independently.
+func (e *Exporter) Clone() *Exporter {
+	// Fields are listed one by one because the runtime state holds locks
+	// and atomics that must not be copied.
+	return &Exporter{
+		Endpoint:                    e.Endpoint,
+		BatchSize:                   e.BatchSize,
+		RetryLimit:                  e.RetryLimit,
+		Transport:                   cloneTransport(e.Transport),
+		Encoder:                     e.Encoder,
+		DryRun:                      e.DryRun,
+		MaxBatchBytes:               e.MaxBatchBytes,
+		MaxRecordsPerRequest:        e.MaxRecordsPerRequest,
+		Concurrency:                 e.Concurrency,
+		Ordered:                     e.Ordered,
+		Dedupe:                      e.Dedupe,
+		MaxAge:                      e.MaxAge,
+		MaxPayloadBytes:             e.MaxPayloadBytes,
+		AttemptTimeout:              e.AttemptTimeout,
+		OnAttempt:                   e.OnAttempt,
+		OnSuccess:                   e.OnSuccess,
+		OnFailure:                   e.OnFailure,
+		OnDeadLetter:                e.OnDeadLetter,
+		Redactor:                    e.Redactor,
+		ConsecutiveFailureThreshold: e.ConsecutiveFailureThreshold,
+		CircuitResetTimeout:         e.CircuitResetTimeout,
+		BaseDelay:                   e.BaseDelay,
+		MaxDelay:                    e.MaxDelay,
+		Jitter:                      e.Jitter,
+		MaxElapsedTime:              e.MaxElapsedTime,
+		Compress:                    e.Compress,
+		CompressMinBytes:            e.CompressMinBytes,
+		RateLimit:                   e.RateLimit,
+		RetryBudget:                 e.RetryBudget,
+		RetryBudgetBurst:            e.RetryBudgetBurst,
+		FailureRatio:                e.FailureRatio,
+	}
+}
+
+// WithEndpoint returns a Clone posting to endpoint instead. An HTTPTransport
+// set as Transport is pointed at endpoint too.
+func (e *Exporter) WithEndpoint(endpoint string) *Exporter {
+	c := e.Clone()
+	c.Endpoint = endpoint
+	switch t := c.Transport.(type) {
+	case HTTPTransport:
+		t.Endpoint = endpoint
+		c.Transport = t
+	case *HTTPTransport:
+		t.Endpoint = endpoint
+	}
+	return c
+}
+
+// cloneTransport copies an HTTPTransport, by value or pointer, with its own
+// Headers map. Other transports are returned as is.
+func cloneTransport(t Transport) Transport {
+	switch t := t.(type) {
+	case HTTPTransport:
+		t.Headers = cloneHeaders(t.Headers)
+		return t
+	case *HTTPTransport:
+		if t == nil {
+			return t
+		}
+		c := *t
+		c.Headers = cloneHeaders(t.Headers)
+		return &c
+	}
+	return t
+}
+
+func cloneHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	c := make(map[string]string, len(headers))
+	for k, v := range headers {
+		c[k] = v
+	}
+	return c
+}
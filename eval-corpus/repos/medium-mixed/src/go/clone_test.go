@@ -0,0 +1,118 @@
+package exporter
+
+import (
+	"reflect"
+	"testing"
+)
+
+// populated returns an Exporter with every exported field set to a
+// non-zero value.
+func populated(t *testing.T) *Exporter {
+	t.Helper()
+	e := &Exporter{}
+	v := reflect.ValueOf(e).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field, sf := v.Field(i), v.Type().Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString("x")
+		case reflect.Int, reflect.Int64:
+			field.SetInt(7)
+		case reflect.Float64:
+			field.SetFloat(0.5)
+		case reflect.Bool:
+			field.SetBool(true)
+		case reflect.Func:
+			field.Set(reflect.MakeFunc(field.Type(), func([]reflect.Value) []reflect.Value {
+				return make([]reflect.Value, field.Type().NumOut())
+			}))
+		case reflect.Interface:
+			switch field.Type() {
+			case reflect.TypeFor[Transport]():
+				field.Set(reflect.ValueOf(&InMemoryTransport{}))
+			case reflect.TypeFor[Encoder]():
+				field.Set(reflect.ValueOf(ProtoEncoder{}))
+			default:
+				t.Fatalf("populated: no value for field %s of type %s", sf.Name, field.Type())
+			}
+		default:
+			t.Fatalf("populated: no value for field %s of kind %s", sf.Name, field.Kind())
+		}
+	}
+	return e
+}
+
+func TestCloneCopiesEveryExportedField(t *testing.T) {
+	e := populated(t)
+	c := e.Clone()
+	ev, cv := reflect.ValueOf(e).Elem(), reflect.ValueOf(c).Elem()
+	for i := 0; i < ev.NumField(); i++ {
+		sf := ev.Type().Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		want, got := ev.Field(i), cv.Field(i)
+		same := false
+		if want.Kind() == reflect.Func {
+			same = got.Pointer() == want.Pointer()
+		} else {
+			same = reflect.DeepEqual(got.Interface(), want.Interface())
+		}
+		if !same {
+			t.Errorf("Clone did not copy %s", sf.Name)
+		}
+	}
+}
+
+func TestCloneCopiesHTTPTransportHeaders(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		transport Transport
+		headers   func(Transport) map[string]string
+	}{
+		{"by value", HTTPTransport{Headers: map[string]string{"X-Team": "a"}}, func(tr Transport) map[string]string { return tr.(HTTPTransport).Headers }},
+		{"by pointer", &HTTPTransport{Headers: map[string]string{"X-Team": "a"}}, func(tr Transport) map[string]string { return tr.(*HTTPTransport).Headers }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Exporter{Transport: tt.transport}
+			c := e.Clone()
+			tt.headers(c.Transport)["X-Team"] = "b"
+			tt.headers(e.Transport)["X-Extra"] = "1"
+			if got := tt.headers(e.Transport); got["X-Team"] != "a" {
+				t.Fatalf("original headers %v changed with the clone's", got)
+			}
+			if got := tt.headers(c.Transport); got["X-Extra"] != "" {
+				t.Fatalf("clone headers %v changed with the original's", got)
+			}
+		})
+	}
+}
+
+func TestCloneSharesOtherTransports(t *testing.T) {
+	tr := &InMemoryTransport{}
+	e := &Exporter{Transport: tr}
+	c := e.Clone()
+	if err := c.SendBatch([]Record{{ID: "a", Payload: "p"}}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Transport != tr || len(tr.Batches) != 1 {
+		t.Fatal("clone does not deliver through the original's transport")
+	}
+	if s := e.Stats(); s != (Stats{}) {
+		t.Fatalf("original Stats = %+v after the clone sent, want zero", s)
+	}
+}
+
+func TestWithEndpoint(t *testing.T) {
+	e := &Exporter{Endpoint: "http://a", Transport: &HTTPTransport{Endpoint: "http://a"}}
+	c := e.WithEndpoint("http://b")
+	if c.Endpoint != "http://b" || c.Transport.(*HTTPTransport).Endpoint != "http://b" {
+		t.Fatalf("WithEndpoint gave %q with transport %+v", c.Endpoint, c.Transport)
+	}
+	if e.Endpoint != "http://a" || e.Transport.(*HTTPTransport).Endpoint != "http://a" {
+		t.Fatal("WithEndpoint changed the original")
+	}
+}
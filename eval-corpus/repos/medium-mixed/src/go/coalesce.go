@@ -0,0 +1,33 @@
+package exporter
+
+// Coalescer merges records that share a key so only the latest state per
+// key is sent. Records whose key is empty are never merged.
+type Coalescer struct {
+	Key func(Record) string
+}
+
+// Coalesce returns records with each key reduced to one record, holding the
+// position of that key's first record and the contents of its last.
+func (c Coalescer) Coalesce(records []Record) []Record {
+	index := make(map[string]int, len(records))
+	merged := make([]Record, 0, len(records))
+	for _, record := range records {
+		merged = c.add(merged, index, record)
+	}
+	return merged
+}
+
+// add appends record to merged, or overwrites the record already there with
+// the same key. index maps keys to their positions in merged.
+func (c Coalescer) add(merged []Record, index map[string]int, record Record) []Record {
+	key := c.Key(record)
+	if key == "" {
+		return append(merged, record)
+	}
+	if i, ok := index[key]; ok {
+		merged[i] = record
+		return merged
+	}
+	index[key] = len(merged)
+	return append(merged, record)
+}
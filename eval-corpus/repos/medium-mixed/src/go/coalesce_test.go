@@ -0,0 +1,78 @@
+package exporter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func byID(r Record) string { return r.ID }
+
+func TestCoalesce(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		records []Record
+		want    []Record
+	}{
+		{"no records", nil, []Record{}},
+		{"distinct keys pass through", []Record{{ID: "a", Payload: "1"}, {ID: "b", Payload: "1"}}, []Record{{ID: "a", Payload: "1"}, {ID: "b", Payload: "1"}}},
+		{"updates collapse to the latest", []Record{{ID: "a", Payload: "1"}, {ID: "a", Payload: "2"}, {ID: "a", Payload: "3"}}, []Record{{ID: "a", Payload: "3"}}},
+		{"merged key keeps its first position", []Record{{ID: "a", Payload: "1"}, {ID: "b", Payload: "1"}, {ID: "a", Payload: "2"}}, []Record{{ID: "a", Payload: "2"}, {ID: "b", Payload: "1"}}},
+		{"empty keys are never merged", []Record{{Payload: "1"}, {ID: "a", Payload: "1"}, {Payload: "2"}}, []Record{{Payload: "1"}, {ID: "a", Payload: "1"}, {Payload: "2"}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Coalescer{Key: byID}).Coalesce(tt.records); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Coalesce = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAsyncExporterCoalesces(t *testing.T) {
+	tr := &InMemoryTransport{}
+	a := NewAsyncExporter(&Exporter{Transport: tr}, time.Hour, nil)
+	// Records buffered before the coalescer is set are merged too.
+	for _, r := range []Record{{ID: "a", Payload: "1"}, {ID: "a", Payload: "2"}} {
+		if err := a.Add(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a.SetCoalescer(&Coalescer{Key: byID})
+	for _, r := range []Record{{ID: "b", Payload: "1"}, {ID: "a", Payload: "3"}, {ID: "b", Payload: "2"}, {ID: "c", Payload: "1"}} {
+		if err := a.Add(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]Record{{{ID: "a", Payload: "3"}, {ID: "b", Payload: "2"}, {ID: "c", Payload: "1"}}}
+	if !reflect.DeepEqual(tr.Batches, want) {
+		t.Fatalf("flushed %v, want %v", tr.Batches, want)
+	}
+}
+
+func TestAsyncExporterCoalescesPerFlush(t *testing.T) {
+	tr := newChanTransport()
+	a := NewAsyncExporter(&Exporter{Transport: tr, BatchSize: 2}, 0, nil)
+	a.SetCoalescer(&Coalescer{Key: byID})
+	// The second a merges into the first rather than filling the batch.
+	addAll(t, a, "aa")
+	tr.idle(t)
+	addAll(t, a, "b")
+	if got := tr.next(t); got != "ab" {
+		t.Fatalf("flushed %q, want ab", got)
+	}
+	// A key sent in an earlier flush is sent again once updated.
+	addAll(t, a, "a")
+	addAll(t, a, "c")
+	if got := tr.next(t); got != "ac" {
+		t.Fatalf("flushed %q, want ac", got)
+	}
+	a.SetCoalescer(nil)
+	addAll(t, a, "dd")
+	if got := tr.next(t); got != "dd" {
+		t.Fatalf("flushed %q after removing the coalescer, want dd", got)
+	}
+	a.Close()
+}
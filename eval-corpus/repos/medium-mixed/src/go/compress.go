@@ -0,0 +1,33 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+)
+
+const defaultCompressMinBytes = 1024
+
+// compress gzips the batch body in place when it meets the compression
+// threshold, recording the encoding and the uncompressed body's checksum for
+// the transport.
+func (e *Exporter) compress(batch *Batch) error {
+	threshold := e.CompressMinBytes
+	if threshold <= 0 {
+		threshold = defaultCompressMinBytes
+	}
+	if len(batch.Body) < threshold {
+		return nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(batch.Body); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	batch.UncompressedChecksum = BatchChecksum(batch.Body)
+	batch.Body = buf.Bytes()
+	batch.ContentEncoding = "gzip"
+	return nil
+}
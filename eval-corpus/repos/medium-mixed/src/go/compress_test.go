@@ -0,0 +1,96 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func gunzip(t *testing.T, body []byte) []byte {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plain
+}
+
+func TestCompress(t *testing.T) {
+	large := []Record{{ID: "a", Payload: strings.Repeat("compressible ", 200)}}
+	small := []Record{{ID: "a", Payload: "p"}}
+	for _, tt := range []struct {
+		name         string
+		e            *Exporter
+		records      []Record
+		wantCompress bool
+	}{
+		{"large body", &Exporter{Compress: true}, large, true},
+		{"below default threshold", &Exporter{Compress: true}, small, false},
+		{"custom threshold", &Exporter{Compress: true, CompressMinBytes: 10}, small, true},
+		{"disabled", &Exporter{}, large, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			batches, err := tt.e.prepare(tt.records)
+			if err != nil {
+				t.Fatal(err)
+			}
+			batch := batches[0]
+			plain, _ := tt.e.Marshal(tt.records)
+			if !tt.wantCompress {
+				if batch.ContentEncoding != "" || !bytes.Equal(batch.Body, plain) {
+					t.Fatalf("body compressed as %q, want it sent as is", batch.ContentEncoding)
+				}
+				return
+			}
+			if batch.ContentEncoding != "gzip" {
+				t.Fatalf("content encoding %q, want gzip", batch.ContentEncoding)
+			}
+			if got := gunzip(t, batch.Body); !bytes.Equal(got, plain) {
+				t.Fatalf("decompressed %q, want %q", got, plain)
+			}
+		})
+	}
+}
+
+func TestCompressShrinksRepetitivePayload(t *testing.T) {
+	e := &Exporter{Compress: true}
+	records := []Record{{ID: "a", Payload: strings.Repeat("compressible ", 200)}}
+	batches, err := e.prepare(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, _ := e.Marshal(records)
+	if got := len(batches[0].Body); got >= len(plain)/4 {
+		t.Fatalf("compressed %d bytes to %d, want far smaller", len(plain), got)
+	}
+}
+
+func TestHTTPTransportSendsContentEncoding(t *testing.T) {
+	records := []Record{{ID: "a", Payload: strings.Repeat("compressible ", 200)}}
+	var encoding string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+	e := &Exporter{Endpoint: srv.URL, Compress: true}
+	if err := e.SendBatch(records); err != nil {
+		t.Fatal(err)
+	}
+	if encoding != "gzip" {
+		t.Fatalf("Content-Encoding %q, want gzip", encoding)
+	}
+	plain, _ := e.Marshal(records)
+	if got := gunzip(t, body); !bytes.Equal(got, plain) {
+		t.Fatalf("endpoint decompressed %q, want %q", got, plain)
+	}
+}
@@ -0,0 +1,72 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Encoder turns a batch of records into a request body and reports the
+// body's content type.
+type Encoder interface {
+	Encode(records []Record) (body []byte, contentType string, err error)
+}
+
+// JSONEncoder encodes a batch as a JSON array. An empty batch encodes as []
+// rather than null.
+type JSONEncoder struct{}
+
+func (JSONEncoder) Encode(records []Record) ([]byte, string, error) {
+	if records == nil {
+		records = []Record{}
+	}
+	body, err := json.Marshal(records)
+	return body, "application/json", err
+}
+
+// Decode parses a body produced by Encode.
+func (JSONEncoder) Decode(body []byte) ([]Record, error) {
+	var records []Record
+	err := json.Unmarshal(body, &records)
+	return records, err
+}
+
+// NDJSONEncoder encodes a batch as newline-delimited JSON: one object per
+// record, each terminated by '\n' as bulk ingestion APIs require. An empty
+// batch encodes as an empty body.
+type NDJSONEncoder struct{}
+
+func (NDJSONEncoder) Encode(records []Record) ([]byte, string, error) {
+	var body []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return nil, "", err
+		}
+		body = append(append(body, line...), '\n')
+	}
+	return body, "application/x-ndjson", nil
+}
+
+// Decode parses a body produced by Encode, ignoring blank lines.
+func (NDJSONEncoder) Decode(body []byte) ([]Record, error) {
+	var records []Record
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func encodeBatch(enc Encoder, records []Record) (Batch, error) {
+	body, contentType, err := enc.Encode(records)
+	if err != nil {
+		return Batch{}, err
+	}
+	return Batch{Records: records, Body: body, ContentType: contentType}, nil
+}
@@ -0,0 +1,149 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarshal(t *testing.T) {
+	e := &Exporter{}
+	for _, tt := range []struct {
+		name    string
+		records []Record
+		want    string
+	}{
+		{"nil batch", nil, `[]`},
+		{"empty batch", []Record{}, `[]`},
+		{"source omitted", []Record{{ID: "a", Payload: "p"}}, `[{"id":"a","payload":"p"}]`},
+		{"field order", []Record{{Source: "s", Payload: "p", ID: "a"}}, `[{"id":"a","payload":"p","source":"s"}]`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := e.Marshal(tt.records)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.want {
+				t.Fatalf("Marshal = %s, want %s", body, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONEncoderRoundTrip(t *testing.T) {
+	records := []Record{
+		{ID: "a", Payload: "p"},
+		{ID: "b", Payload: `quoted "payload"`, Source: "s", Sequence: 7},
+	}
+	body, contentType, err := JSONEncoder{}.Encode(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "application/json" {
+		t.Fatalf("content type %q, want application/json", contentType)
+	}
+	got, err := JSONEncoder{}.Decode(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, records) {
+		t.Fatalf("round trip gave %+v, want %+v", got, records)
+	}
+}
+
+func TestSendBatchDeliversMarshaledBody(t *testing.T) {
+	var bodies []string
+	tr := transportFunc(func(ctx context.Context, batch Batch) error {
+		bodies = append(bodies, string(batch.Body))
+		return nil
+	})
+	records := []Record{{ID: "a", Payload: "p", Source: "s"}}
+	if err := (&Exporter{Transport: tr}).SendBatch(records); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := (&Exporter{}).Marshal(records)
+	if len(bodies) != 1 || bodies[0] != string(want) {
+		t.Fatalf("delivered %q, want %s", bodies, want)
+	}
+}
+
+func benchmarkEncoder(b *testing.B, enc Encoder) {
+	records := make([]Record, 100)
+	for i := range records {
+		records[i] = Record{ID: fmt.Sprintf("record-%d", i), Payload: strings.Repeat("x", 200), Source: "bench", Timestamp: time.Unix(1700000000, 0)}
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, _, err := enc.Encode(records); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJSONEncoder(b *testing.B)  { benchmarkEncoder(b, JSONEncoder{}) }
+func BenchmarkProtoEncoder(b *testing.B) { benchmarkEncoder(b, ProtoEncoder{}) }
+
+func TestNDJSONEncoder(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		records []Record
+	}{
+		{"empty batch", nil},
+		{"one record", []Record{{ID: "a", Payload: "p"}}},
+		{"newlines in values", []Record{{ID: "a", Payload: "line one\nline two"}, {ID: "b", Payload: "p", Source: "s", Sequence: 3}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType, err := NDJSONEncoder{}.Encode(tt.records)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if contentType != "application/x-ndjson" {
+				t.Fatalf("content type %q, want application/x-ndjson", contentType)
+			}
+			if len(tt.records) == 0 && len(body) != 0 {
+				t.Fatalf("empty batch encoded as %q, want an empty body", body)
+			}
+			if lines := strings.Count(string(body), "\n"); lines != len(tt.records) || len(body) > 0 && body[len(body)-1] != '\n' {
+				t.Fatalf("body %q has %d newline-terminated lines, want %d", body, lines, len(tt.records))
+			}
+			got, err := NDJSONEncoder{}.Decode(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.records) {
+				t.Fatalf("round trip gave %+v, want %+v", got, tt.records)
+			}
+		})
+	}
+}
+
+func TestNDJSONDecodeSkipsBlankLines(t *testing.T) {
+	got, err := NDJSONEncoder{}.Decode([]byte("\n{\"id\":\"a\",\"payload\":\"p\"}\n  \n{\"id\":\"b\",\"payload\":\"p\"}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Record{{ID: "a", Payload: "p"}, {ID: "b", Payload: "p"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Decode = %+v, want %+v", got, want)
+	}
+	if _, err := (NDJSONEncoder{}).Decode([]byte("{\"id\":")); err == nil {
+		t.Fatal("Decode accepted a truncated line")
+	}
+}
+
+func TestSendBatchUsesEncoderContentType(t *testing.T) {
+	var got Batch
+	tr := transportFunc(func(ctx context.Context, batch Batch) error {
+		got = batch
+		return nil
+	})
+	e := &Exporter{Transport: tr, Encoder: NDJSONEncoder{}}
+	if err := e.SendBatch([]Record{{ID: "a", Payload: "p"}, {ID: "b", Payload: "p"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got.ContentType != "application/x-ndjson" || strings.Count(string(got.Body), "\n") != 2 {
+		t.Fatalf("delivered %q as %q, want two NDJSON lines", got.Body, got.ContentType)
+	}
+}
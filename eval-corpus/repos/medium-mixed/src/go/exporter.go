@@ -1,39 +1,729 @@
 package exporter
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math"
+	"math/rand"
+	"sync/atomic"
 	"time"
 )
 
+// ErrRecordTooLarge is returned when a single record cannot fit within
+// MaxBatchBytes on its own.
+var ErrRecordTooLarge = errors.New("record exceeds max batch bytes")
+
+// ErrRecordExpired is passed to OnDeadLetter with records dropped for being
+// older than MaxAge.
+var ErrRecordExpired = errors.New("record older than max age")
+
+// ErrRetryBudgetExhausted is returned when a batch needed a retry but the
+// Exporter's RetryBudget had none left.
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// BatchSendError reports a batch that could not be delivered: how many
+// records it held, how many attempts were made and the error that ended
+// them.
+type BatchSendError struct {
+	BatchSize int
+	Attempts  int
+	Err       error
+}
+
+func (b *BatchSendError) Error() string {
+	return fmt.Sprintf("send failed after %d attempts for batch of %d records: %v", b.Attempts, b.BatchSize, b.Err)
+}
+
+func (b *BatchSendError) Unwrap() error { return b.Err }
+
 type Record struct {
-	ID      string
-	Payload string
-	Source  string
+	ID      string `json:"id"`
+	Payload string `json:"payload"`
+	Source  string `json:"source,omitempty"`
+	// Sequence is assigned by SendBatchOrdered so the endpoint can restore
+	// send order; zero means unsequenced.
+	Sequence uint64 `json:"sequence,omitempty"`
+	// Timestamp is when the record was produced, checked against MaxAge.
+	// A zero Timestamp never expires.
+	Timestamp time.Time `json:"timestamp,omitzero"`
 }
 
+// Exporter sends batches of records to Endpoint. An Exporter must not be
+// copied after first use; its methods take a pointer and go vet's copylocks
+// check reports copies.
 type Exporter struct {
-	Endpoint   string
-	BatchSize  int
+	Endpoint  string
+	BatchSize int
+	// RetryLimit is the number of delivery attempts per batch; values below
+	// 1 still make a single attempt.
 	RetryLimit int
+	// Transport delivers each attempt; nil means an HTTPTransport posting to
+	// Endpoint.
+	Transport Transport
+	// Encoder produces request bodies; nil means JSONEncoder.
+	Encoder Encoder
+	// DryRun validates and encodes every send and fires OnAttempt and
+	// OnSuccess as for a first attempt that succeeds, but never calls the
+	// transport and leaves the circuit breaker, rate limits and Stats
+	// untouched.
+	DryRun bool
+	// MaxBatchBytes splits batches whose encoded body would exceed this
+	// many bytes into sequential sub-batches. Zero means no limit.
+	MaxBatchBytes int
+	// MaxRecordsPerRequest splits batches with more records than this into
+	// sequential sub-batches, independently of BatchSize and before
+	// MaxBatchBytes applies. Zero means no limit.
+	MaxRecordsPerRequest int
+	// Concurrency bounds how many batches SendAll delivers at once.
+	// Values below 1 mean sequential delivery.
+	Concurrency int
+	// Ordered makes SendAll deliver strictly in order: each batch, including
+	// every sub-batch split from it, completes before the next
+	// starts, and the first one that fails ends the send. Concurrency is
+	// ignored.
+	Ordered bool
+	// Dedupe drops records with repeated IDs before sending; see
+	// DedupeRecords.
+	Dedupe bool
+	// MaxAge drops records whose Timestamp is older than this before
+	// sending. Dropped records go to OnDeadLetter with ErrRecordExpired and
+	// are counted in Stats. Zero means records never expire.
+	MaxAge time.Duration
+	// MaxPayloadBytes rejects records whose payload is longer than this in
+	// ValidateRecords. Zero means no limit.
+	MaxPayloadBytes int
+	// AttemptTimeout bounds each delivery attempt. A timed-out attempt is
+	// retried like any transient failure. Zero means no per-attempt limit.
+	AttemptTimeout time.Duration
+
+	// Optional hooks for observing delivery. OnAttempt fires before each
+	// attempt, OnFailure after each failed one, and OnSuccess once a batch
+	// is delivered with the time spent across all of its attempts.
+	OnAttempt func(attempt int, batchSize int)
+	OnSuccess func(batchSize int, elapsed time.Duration)
+	OnFailure func(attempt int, err error)
+	// OnDeadLetter receives each batch that could not be delivered, with the
+	// error that ended its delivery, before the send returns. It gets the
+	// original records, not their redacted form, so they can be replayed.
+	OnDeadLetter func(records []Record, err error)
+	// Redactor, if set, rewrites records before they appear in an error
+	// message, for example to mask sensitive IDs. Delivery and OnDeadLetter
+	// always use the original records.
+	Redactor func(Record) Record
+
+	// ConsecutiveFailureThreshold enables a circuit breaker: after this many
+	// consecutive failed batches, sends fail fast with ErrCircuitOpen until
+	// CircuitResetTimeout has passed, then a single probe batch decides
+	// whether the circuit closes again. Zero disables the breaker.
+	ConsecutiveFailureThreshold int
+	CircuitResetTimeout         time.Duration
+
+	// BaseDelay enables exponential backoff between attempts, doubling per
+	// attempt up to MaxDelay (zero means uncapped). When zero, the legacy
+	// linear attempt*50ms delay is used. A Retry-After sent by the endpoint
+	// replaces the backoff delay but is still capped at MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Jitter randomizes each delay to between half and all of its value.
+	Jitter bool
+	// MaxElapsedTime stops retrying once a SendBatch call has spent this
+	// long across attempts and backoff, whichever of it and RetryLimit
+	// comes first. Zero means only RetryLimit applies.
+	MaxElapsedTime time.Duration
+
+	// Compress gzips request bodies of at least CompressMinBytes (1 KiB when
+	// zero); smaller bodies are sent as is.
+	Compress         bool
+	CompressMinBytes int
+
+	// RateLimit caps delivery attempts per second across all sends from
+	// this Exporter. Zero means unlimited.
+	RateLimit float64
+	// RetryBudget caps retries per second across all sends from this
+	// Exporter, saving up to RetryBudgetBurst (at least 1) while idle. A
+	// failed attempt that finds the budget spent ends its batch with
+	// ErrRetryBudgetExhausted instead of retrying. Zero means unlimited.
+	RetryBudget      float64
+	RetryBudgetBurst int
+	// FailureRatio is the fraction of records SendBatchResilient may leave
+	// undelivered before it fails the whole batch. Zero tolerates none.
+	FailureRatio float64
+
+	breaker circuitBreaker
+	stats   counters
+	limiter tokenBucket
+	budget  tokenBucket
+	// sequence is the last sequence number handed out by SendBatchOrdered.
+	sequence atomic.Uint64
+}
+
+func (e *Exporter) SendBatch(records []Record) error {
+	return e.SendBatchContext(context.Background(), records)
+}
+
+// SendBatchOrdered sends records like SendBatch, stamping copies of them
+// with the next contiguous block of sequence numbers from this Exporter.
+// Numbers start at 1, increase across calls and are kept through retries
+// and splits. They are handed out only once validation, Dedupe and MaxAge
+// have run, so records dropped before sending take none. A batch that fails
+// to encode or deliver still uses up its numbers, and the endpoint sees the
+// gap.
+func (e *Exporter) SendBatchOrdered(records []Record) error {
+	_, err := e.send(context.Background(), records, nil, true)
+	return err
+}
+
+// SendBatchContext is SendBatch with cancellation: a done ctx aborts the
+// retry loop, including any backoff currently in progress. Batches split by
+// MaxRecordsPerRequest or MaxBatchBytes are sent in order and their errors
+// joined.
+func (e *Exporter) SendBatchContext(ctx context.Context, records []Record) error {
+	_, err := e.send(ctx, records, nil, false)
+	return err
+}
+
+// SendBatchN is SendBatch that also reports how many records were delivered,
+// which can be non-zero on error when MaxRecordsPerRequest or MaxBatchBytes
+// splits the batch and only some sub-batches succeed.
+func (e *Exporter) SendBatchN(records []Record) (sent int, err error) {
+	return e.send(context.Background(), records, nil, false)
+}
+
+// Ping checks that the destination is reachable without sending records,
+// for gating startup on connectivity. Transports that do not implement
+// Pinger are assumed reachable.
+func (e *Exporter) Ping(ctx context.Context) error {
+	if pinger, ok := e.transport().(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
 }
 
-func (e Exporter) SendBatch(records []Record) error {
+// send delivers records and reports how many were delivered. When results
+// is non-nil it holds one entry per record and receives each record's
+// outcome; a record dropped by Dedupe shares the outcome of the later record
+// that replaced it, and one dropped by MaxAge gets ErrRecordExpired. With
+// sequence set, the records left to send are numbered as SendBatchOrdered
+// describes.
+func (e *Exporter) send(ctx context.Context, records []Record, results []RecordResult, sequence bool) (int, error) {
 	if len(records) == 0 {
-		return errors.New("empty batch")
+		return 0, errors.New("empty batch")
+	}
+	for i := range results {
+		results[i] = RecordResult{Record: records[i]}
+	}
+	if err := ctx.Err(); err != nil {
+		setResults(results, nil, err)
+		return 0, err
+	}
+	started := time.Now()
+	if err := e.ValidateRecords(records); err != nil {
+		setResults(results, nil, err)
+		return 0, err
+	}
+	// index holds the positions in records of those still to be sent.
+	var index []int
+	if e.Dedupe {
+		index = dedupeIndex(records)
+		if results != nil {
+			defer shareDuplicateResults(records, results)
+		}
+	} else {
+		index = make([]int, len(records))
+		for i := range index {
+			index[i] = i
+		}
+	}
+	if e.MaxAge > 0 {
+		var expired []int
+		index, expired = e.expire(records, index)
+		setResults(results, expired, ErrRecordExpired)
+		if len(index) == 0 {
+			return 0, nil
+		}
+	}
+	kept := make([]Record, len(index))
+	for j, i := range index {
+		kept[j] = records[i]
+	}
+	if sequence {
+		e.stampSequence(kept)
+	}
+	batches, err := e.prepare(kept)
+	if err != nil {
+		setResults(results, index, err)
+		return 0, err
+	}
+	if e.DryRun {
+		for _, batch := range batches {
+			e.dryRun(batch)
+		}
+		return len(kept), nil
+	}
+	transport := e.transport()
+	sent := 0
+	var errs []error
+	// Batches hold the kept records in order, so next walks index alongside
+	// them.
+	next := 0
+	for _, batch := range batches {
+		err := e.deliver(ctx, transport, batch, started)
+		setResults(results, index[next:next+len(batch.Records)], err)
+		next += len(batch.Records)
+		if err != nil {
+			errs = append(errs, err)
+			if ctx.Err() != nil || e.Ordered {
+				// Batches never attempted fail with the error that
+				// stopped the send.
+				setResults(results, index[next:], err)
+				break
+			}
+			continue
+		}
+		sent += len(batch.Records)
+	}
+	if len(errs) == 1 {
+		return sent, errs[0]
 	}
+	return sent, errors.Join(errs...)
+}
+
+// stampSequence numbers records with the next block of sequence numbers. A
+// dry run shows the numbers the send would take without using them up.
+func (e *Exporter) stampSequence(records []Record) {
+	n := uint64(len(records))
+	var last uint64
+	if e.DryRun {
+		last = e.sequence.Load() + n
+	} else {
+		last = e.sequence.Add(n)
+	}
+	for i := range records {
+		records[i].Sequence = last - n + 1 + uint64(i)
+	}
+}
+
+// setResults records err as the outcome of the records at index, or of every
+// record when index is nil. It does nothing when results is nil.
+func setResults(results []RecordResult, index []int, err error) {
+	if results == nil {
+		return
+	}
+	if index == nil {
+		for i := range results {
+			results[i].Err = err
+		}
+		return
+	}
+	for _, i := range index {
+		results[i].Err = err
+	}
+}
+
+// prepare turns records into the batches to deliver, in order: split by
+// MaxRecordsPerRequest and MaxBatchBytes, keyed and compressed.
+func (e *Exporter) prepare(records []Record) ([]Batch, error) {
+	var batches []Batch
+	for _, group := range chunk(records, e.MaxRecordsPerRequest) {
+		split, err := e.split(group)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, split...)
+	}
+	for i := range batches {
+		batches[i].IdempotencyKey = IdempotencyKey(batches[i].Records)
+	}
+	if e.Compress {
+		for i := range batches {
+			if err := e.compress(&batches[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return batches, nil
+}
+
+// dryRun stands in for deliver under DryRun.
+func (e *Exporter) dryRun(batch Batch) {
+	if e.OnAttempt != nil {
+		e.OnAttempt(1, len(batch.Records))
+	}
+	if e.OnSuccess != nil {
+		e.OnSuccess(len(batch.Records), 0)
+	}
+}
+
+// deliver sends one batch through the circuit breaker, handing it to
+// OnDeadLetter if it cannot be delivered.
+func (e *Exporter) deliver(ctx context.Context, transport Transport, batch Batch, started time.Time) error {
+	err := e.breaker.allow(e.ConsecutiveFailureThreshold, e.CircuitResetTimeout)
+	if err == nil {
+		err = e.retry(ctx, transport, batch, started)
+		e.breaker.record(e.ConsecutiveFailureThreshold, err, ctx.Err() != nil)
+	}
+	if err != nil {
+		e.stats.failures.Add(1)
+		if e.OnDeadLetter != nil {
+			e.OnDeadLetter(batch.Records, err)
+		}
+	}
+	return err
+}
+
+// retry sends one batch, retrying retryable failures up to RetryLimit
+// attempts (at least one) or until the next backoff would run past
+// MaxElapsedTime since started. A batch that is not delivered is reported
+// as a *BatchSendError.
+func (e *Exporter) retry(ctx context.Context, transport Transport, batch Batch, started time.Time) error {
+	size := func() int { return len(batch.Records) }
+	return e.retryAttempts(ctx, started, size, func(ctx context.Context) (int, error) {
+		if err := e.attempt(ctx, transport, batch); err != nil {
+			return 0, err
+		}
+		return len(batch.Records), nil
+	})
+}
+
+// retryAttempts is the retry loop behind retry and SendBatchResilient. Each
+// try attempts the records still pending, which number pending(), and
+// returns how many it delivered; a nil error means none remain. Every
+// attempt waits for RateLimit and every retry draws on RetryBudget.
+func (e *Exporter) retryAttempts(ctx context.Context, started time.Time, pending func() int, try func(context.Context) (int, error)) error {
+	start := time.Now()
+	size := pending()
+	attempts := e.RetryLimit
+	if attempts < 1 {
+		attempts = 1
+	}
+	sent := 0
 	var lastErr error
-	for attempt := 1; attempt <= e.RetryLimit; attempt++ {
-		if e.Endpoint == "" {
-			lastErr = errors.New("missing endpoint")
-		} else {
-			lastErr = nil
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err := e.limiter.wait(ctx, e.RateLimit, 1); err != nil {
+			return &BatchSendError{BatchSize: size, Attempts: attempt - 1, Err: err}
+		}
+		if attempt > 1 {
+			e.stats.retries.Add(1)
+		}
+		if e.OnAttempt != nil {
+			e.OnAttempt(attempt, pending())
+		}
+		var n int
+		n, lastErr = try(ctx)
+		sent += n
+		e.stats.recordsSent.Add(int64(n))
+		if lastErr == nil {
+			e.stats.batchesSent.Add(1)
+			if e.OnSuccess != nil {
+				e.OnSuccess(sent, time.Since(start))
+			}
+			break
+		}
+		if e.OnFailure != nil {
+			e.OnFailure(attempt, lastErr)
+		}
+		if !IsRetryable(lastErr) {
+			return &BatchSendError{BatchSize: size, Attempts: attempt, Err: lastErr}
+		}
+		if attempt == attempts {
 			break
 		}
-		time.Sleep(time.Duration(attempt) * 50 * time.Millisecond)
+		if !e.budget.take(e.RetryBudget, e.RetryBudgetBurst) {
+			err := fmt.Errorf("%w: %w", ErrRetryBudgetExhausted, lastErr)
+			return &BatchSendError{BatchSize: size, Attempts: attempt, Err: err}
+		}
+		delay := e.backoff(attempt)
+		if d := retryAfter(lastErr); d > 0 {
+			delay = d
+			if e.MaxDelay > 0 && delay > e.MaxDelay {
+				delay = e.MaxDelay
+			}
+		}
+		if elapsed := time.Since(started); e.MaxElapsedTime > 0 && elapsed+delay > e.MaxElapsedTime {
+			err := fmt.Errorf("gave up after %s: %w", elapsed.Round(time.Millisecond), lastErr)
+			return &BatchSendError{BatchSize: size, Attempts: attempt, Err: err}
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return &BatchSendError{BatchSize: size, Attempts: attempt, Err: err}
+		}
 	}
 	if lastErr != nil {
-		return fmt.Errorf("send failed after %d attempts: %w", e.RetryLimit, lastErr)
+		return &BatchSendError{BatchSize: size, Attempts: attempts, Err: lastErr}
 	}
 	return nil
 }
+
+// attempt runs a single Deliver under AttemptTimeout.
+func (e *Exporter) attempt(ctx context.Context, transport Transport, batch Batch) error {
+	return e.withAttemptTimeout(ctx, func(ctx context.Context) error {
+		return transport.Deliver(ctx, batch)
+	})
+}
+
+// withAttemptTimeout runs deliver under AttemptTimeout. The call is
+// abandoned rather than awaited once the timeout fires, so a transport that
+// ignores its context cannot stall the retry loop.
+func (e *Exporter) withAttemptTimeout(ctx context.Context, deliver func(ctx context.Context) error) error {
+	if e.AttemptTimeout <= 0 {
+		return deliver(ctx)
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, e.AttemptTimeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- deliver(attemptCtx) }()
+	var err error
+	select {
+	case err = <-done:
+		if err == nil {
+			return nil
+		}
+	case <-attemptCtx.Done():
+		err = attemptCtx.Err()
+	}
+	if attemptCtx.Err() != nil && ctx.Err() == nil {
+		return RetryableError{Err: fmt.Errorf("attempt timed out after %s: %w", e.AttemptTimeout, context.DeadlineExceeded)}
+	}
+	return err
+}
+
+// split encodes records into batches whose bodies fit within MaxBatchBytes,
+// preserving record order. Without a limit the records form a single batch.
+func (e *Exporter) split(records []Record) ([]Batch, error) {
+	enc := e.encoder()
+	batch, err := encodeBatch(enc, records)
+	if err != nil {
+		return nil, err
+	}
+	if e.MaxBatchBytes <= 0 || len(batch.Body) <= e.MaxBatchBytes {
+		return []Batch{batch}, nil
+	}
+	// Group sizes are estimated from each record's own encoding plus the
+	// framing of an empty batch and the separator between two records, so
+	// records only need encoding once before grouping.
+	empty, _, err := enc.Encode(nil)
+	if err != nil {
+		return nil, err
+	}
+	sizes := make([]int, len(records))
+	for i, record := range records {
+		one, _, err := enc.Encode([]Record{record})
+		if err != nil {
+			return nil, err
+		}
+		if len(one) > e.MaxBatchBytes {
+			return nil, fmt.Errorf("record %q encodes to %d bytes, limit %d: %w", e.redactRecord(record).ID, len(one), e.MaxBatchBytes, ErrRecordTooLarge)
+		}
+		sizes[i] = len(one) - len(empty)
+	}
+	sep := 0
+	if len(records) > 1 {
+		two, _, err := enc.Encode(records[:2])
+		if err != nil {
+			return nil, err
+		}
+		if sep = len(two) - len(empty) - sizes[0] - sizes[1]; sep < 0 {
+			sep = 0
+		}
+	}
+	var batches []Batch
+	start, size := 0, len(empty)
+	for i := range records {
+		n := sizes[i]
+		if i > start {
+			n += sep
+		}
+		if size+n > e.MaxBatchBytes {
+			if batches, err = e.pack(enc, batches, records[start:i]); err != nil {
+				return nil, err
+			}
+			start, size, n = i, len(empty), sizes[i]
+		}
+		size += n
+	}
+	return e.pack(enc, batches, records[start:])
+}
+
+// pack encodes group as the next batch. Should the size estimate prove
+// optimistic for the encoder, the group is halved until each part fits.
+func (e *Exporter) pack(enc Encoder, batches []Batch, group []Record) ([]Batch, error) {
+	batch, err := encodeBatch(enc, group)
+	if err != nil {
+		return nil, err
+	}
+	if len(batch.Body) <= e.MaxBatchBytes || len(group) == 1 {
+		return append(batches, batch), nil
+	}
+	mid := len(group) / 2
+	if batches, err = e.pack(enc, batches, group[:mid]); err != nil {
+		return nil, err
+	}
+	return e.pack(enc, batches, group[mid:])
+}
+
+// Marshal encodes records as a JSON array with fields in declaration order,
+// regardless of Encoder. An empty batch encodes as [] rather than null.
+func (e *Exporter) Marshal(records []Record) ([]byte, error) {
+	body, _, err := JSONEncoder{}.Encode(records)
+	return body, err
+}
+
+func (e *Exporter) encoder() Encoder {
+	if e.Encoder != nil {
+		return e.Encoder
+	}
+	return JSONEncoder{}
+}
+
+// expire splits the records at index into those within MaxAge and those
+// older, handing the latter to OnDeadLetter.
+func (e *Exporter) expire(records []Record, index []int) (fresh, expired []int) {
+	cutoff := time.Now().Add(-e.MaxAge)
+	fresh = make([]int, 0, len(index))
+	var stale []Record
+	for _, i := range index {
+		if record := records[i]; !record.Timestamp.IsZero() && record.Timestamp.Before(cutoff) {
+			stale = append(stale, record)
+			expired = append(expired, i)
+			continue
+		}
+		fresh = append(fresh, i)
+	}
+	if len(stale) > 0 {
+		if !e.DryRun {
+			e.stats.expired.Add(int64(len(stale)))
+		}
+		if e.OnDeadLetter != nil {
+			e.OnDeadLetter(stale, ErrRecordExpired)
+		}
+	}
+	return fresh, expired
+}
+
+// redactRecord returns record passed through Redactor, or record itself when
+// no Redactor is set.
+func (e *Exporter) redactRecord(record Record) Record {
+	if e.Redactor == nil {
+		return record
+	}
+	return e.Redactor(record)
+}
+
+func (e *Exporter) transport() Transport {
+	if e.Transport != nil {
+		return e.Transport
+	}
+	return HTTPTransport{Endpoint: e.Endpoint}
+}
+
+func (e *Exporter) backoff(attempt int) time.Duration {
+	if e.BaseDelay <= 0 {
+		return time.Duration(attempt) * 50 * time.Millisecond
+	}
+	d := e.BaseDelay
+	for i := 1; i < attempt; i++ {
+		if e.MaxDelay > 0 && d >= e.MaxDelay {
+			break
+		}
+		if d > math.MaxInt64/2 {
+			d = math.MaxInt64
+			break
+		}
+		d *= 2
+	}
+	if e.MaxDelay > 0 && d > e.MaxDelay {
+		d = e.MaxDelay
+	}
+	if e.Jitter {
+		half := d / 2
+		d = half + time.Duration(rand.Int63n(int64(d-half)+1))
+	}
+	return d
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RecordResult is the delivery outcome of a single record; Err is nil on
+// success.
+type RecordResult struct {
+	Record Record
+	Err    error
+}
+
+// SendBatchDetailed sends records like SendBatch and reports the outcome of
+// each record. When MaxRecordsPerRequest or MaxBatchBytes splits the batch,
+// each record carries the error of the sub-batch it was sent in, so only
+// records that were not delivered appear in FailedRecords.
+func (e *Exporter) SendBatchDetailed(records []Record) ([]RecordResult, error) {
+	results := make([]RecordResult, len(records))
+	_, err := e.send(context.Background(), records, results, false)
+	return results, err
+}
+
+// FailedRecords returns the records whose delivery failed, in order.
+func FailedRecords(results []RecordResult) []Record {
+	var failed []Record
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result.Record)
+		}
+	}
+	return failed
+}
+
+// DedupeRecords keeps only the last occurrence of each record ID, ordered by
+// the position of that last occurrence. Records with an empty ID are never
+// dropped.
+func DedupeRecords(records []Record) []Record {
+	index := dedupeIndex(records)
+	kept := make([]Record, len(index))
+	for j, i := range index {
+		kept[j] = records[i]
+	}
+	return kept
+}
+
+// dedupeIndex returns the positions of the records DedupeRecords keeps, in
+// ascending order.
+func dedupeIndex(records []Record) []int {
+	seen := make(map[string]bool, len(records))
+	index := make([]int, 0, len(records))
+	for i := len(records) - 1; i >= 0; i-- {
+		if id := records[i].ID; id != "" {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+		}
+		index = append(index, i)
+	}
+	for i, j := 0, len(index)-1; i < j; i, j = i+1, j-1 {
+		index[i], index[j] = index[j], index[i]
+	}
+	return index
+}
+
+// shareDuplicateResults gives each record DedupeRecords would drop the
+// outcome of the last record with its ID, which was sent in its place.
+func shareDuplicateResults(records []Record, results []RecordResult) {
+	last := make(map[string]int, len(records))
+	for i, record := range records {
+		if record.ID != "" {
+			last[record.ID] = i
+		}
+	}
+	for i, record := range records {
+		if j, ok := last[record.ID]; ok && j != i {
+			results[i].Err = results[j].Err
+		}
+	}
+}
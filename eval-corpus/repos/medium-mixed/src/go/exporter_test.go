@@ -0,0 +1,723 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// transportFunc adapts a function to Transport.
+type transportFunc func(ctx context.Context, batch Batch) error
+
+func (f transportFunc) Deliver(ctx context.Context, batch Batch) error { return f(ctx, batch) }
+
+func TestBackoffNeverExceedsMaxDelay(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		e    *Exporter
+	}{
+		{"exponential", &Exporter{BaseDelay: 10 * time.Millisecond, MaxDelay: 70 * time.Millisecond}},
+		{"jitter", &Exporter{BaseDelay: 10 * time.Millisecond, MaxDelay: 70 * time.Millisecond, Jitter: true}},
+		{"max below base", &Exporter{BaseDelay: time.Second, MaxDelay: time.Millisecond}},
+		{"overflow", &Exporter{BaseDelay: time.Hour, MaxDelay: 1000 * time.Hour}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for attempt := 1; attempt <= 100; attempt++ {
+				if d := tt.e.backoff(attempt); d <= 0 || d > tt.e.MaxDelay {
+					t.Fatalf("backoff(%d) = %s, want in (0, %s]", attempt, d, tt.e.MaxDelay)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoffDoublesPerAttempt(t *testing.T) {
+	e := &Exporter{BaseDelay: 10 * time.Millisecond, MaxDelay: 70 * time.Millisecond}
+	for _, tt := range []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 10 * time.Millisecond},
+		{2, 20 * time.Millisecond},
+		{3, 40 * time.Millisecond},
+		{4, 70 * time.Millisecond},
+		{5, 70 * time.Millisecond},
+	} {
+		if got := e.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestBackoffWithoutBaseDelayIsLinear(t *testing.T) {
+	e := &Exporter{MaxDelay: time.Millisecond, Jitter: true}
+	for attempt := 1; attempt <= 3; attempt++ {
+		if got, want := e.backoff(attempt), time.Duration(attempt)*50*time.Millisecond; got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
+
+func TestBackoffJitterVariesWithinHalfToFull(t *testing.T) {
+	e := &Exporter{BaseDelay: 10 * time.Millisecond, Jitter: true}
+	// The third attempt waits 40ms before jitter.
+	d := 40 * time.Millisecond
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 200; i++ {
+		got := e.backoff(3)
+		if got < d/2 || got > d {
+			t.Fatalf("backoff(3) = %s, want in [%s, %s]", got, d/2, d)
+		}
+		seen[got] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("jitter gave %d distinct delays in 200 runs, want varied values", len(seen))
+	}
+}
+
+// encodedSize is the length of records encoded as a JSON batch.
+func encodedSize(t *testing.T, records ...Record) int {
+	t.Helper()
+	body, err := (&Exporter{}).Marshal(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(body)
+}
+
+func TestMaxBatchBytesSplitsAtBoundary(t *testing.T) {
+	records := []Record{
+		{ID: "a", Payload: strings.Repeat("x", 10)},
+		{ID: "b", Payload: strings.Repeat("x", 100)},
+		{ID: "c", Payload: strings.Repeat("x", 1)},
+		{ID: "d", Payload: strings.Repeat("x", 50)},
+	}
+	whole := encodedSize(t, records...)
+	largest := encodedSize(t, records[1])
+	for _, tt := range []struct {
+		name  string
+		limit int
+		want  []string
+	}{
+		{"no limit", 0, []string{"abcd"}},
+		{"whole batch fits exactly", whole, []string{"abcd"}},
+		{"one byte under whole batch", whole - 1, []string{"abc", "d"}},
+		{"first three fit exactly", encodedSize(t, records[:3]...), []string{"abc", "d"}},
+		{"one byte under first three", encodedSize(t, records[:3]...) - 1, []string{"ab", "cd"}},
+		{"largest record fits exactly", largest, []string{"a", "b", "cd"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			tr := transportFunc(func(ctx context.Context, batch Batch) error {
+				if tt.limit > 0 && len(batch.Body) > tt.limit {
+					t.Errorf("body of %d bytes exceeds limit %d", len(batch.Body), tt.limit)
+				}
+				var ids string
+				for _, record := range batch.Records {
+					ids += record.ID
+				}
+				got = append(got, ids)
+				return nil
+			})
+			e := &Exporter{Transport: tr, MaxBatchBytes: tt.limit}
+			if err := e.SendBatch(records); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("sent batches %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxBatchBytesRejectsOversizedRecord(t *testing.T) {
+	records := []Record{
+		{ID: "small", Payload: "x"},
+		{ID: "big", Payload: strings.Repeat("x", 100)},
+	}
+	var delivered int
+	tr := transportFunc(func(ctx context.Context, batch Batch) error {
+		delivered++
+		return nil
+	})
+	e := &Exporter{Transport: tr, MaxBatchBytes: encodedSize(t, records[1]) - 1}
+	err := e.SendBatch(records)
+	if !errors.Is(err, ErrRecordTooLarge) {
+		t.Fatalf("SendBatch = %v, want ErrRecordTooLarge", err)
+	}
+	if !strings.Contains(err.Error(), `"big"`) {
+		t.Fatalf("error %q does not name the record", err)
+	}
+	if delivered != 0 {
+		t.Fatalf("delivered %d batches, want none", delivered)
+	}
+}
+
+func TestDedupeRecords(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		records []Record
+		want    []Record
+	}{
+		{"no duplicates", []Record{{ID: "a"}, {ID: "b"}}, []Record{{ID: "a"}, {ID: "b"}}},
+		{
+			"interleaved duplicates keep last occurrence",
+			[]Record{{ID: "a", Payload: "1"}, {ID: "b", Payload: "1"}, {ID: "a", Payload: "2"}, {ID: "c", Payload: "1"}, {ID: "b", Payload: "2"}},
+			[]Record{{ID: "a", Payload: "2"}, {ID: "c", Payload: "1"}, {ID: "b", Payload: "2"}},
+		},
+		{
+			"empty IDs are never dropped",
+			[]Record{{Payload: "1"}, {ID: "a", Payload: "1"}, {Payload: "2"}, {ID: "a", Payload: "2"}},
+			[]Record{{Payload: "1"}, {Payload: "2"}, {ID: "a", Payload: "2"}},
+		},
+		{"empty batch", nil, []Record{}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DedupeRecords(tt.records); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("DedupeRecords = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendBatchDedupe(t *testing.T) {
+	records := []Record{{ID: "a", Payload: "1"}, {ID: "b", Payload: "1"}, {ID: "a", Payload: "2"}}
+	for _, tt := range []struct {
+		dedupe bool
+		want   []Record
+	}{
+		{false, records},
+		{true, []Record{{ID: "b", Payload: "1"}, {ID: "a", Payload: "2"}}},
+	} {
+		tr := &InMemoryTransport{}
+		if err := (&Exporter{Transport: tr, Dedupe: tt.dedupe}).SendBatch(records); err != nil {
+			t.Fatal(err)
+		}
+		if len(tr.Batches) != 1 || !reflect.DeepEqual(tr.Batches[0], tt.want) {
+			t.Fatalf("Dedupe %v sent %+v, want %+v", tt.dedupe, tr.Batches, tt.want)
+		}
+	}
+}
+
+func TestAttemptTimeout(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	for _, tt := range []struct {
+		name string
+		// slow reports whether the given attempt outlasts the timeout.
+		slow         func(attempt int32) bool
+		ignoresCtx   bool
+		wantErr      bool
+		wantAttempts int32
+	}{
+		{"every attempt times out", func(int32) bool { return true }, false, true, 3},
+		{"transport ignores its context", func(int32) bool { return true }, true, true, 3},
+		{"retry after a timeout succeeds", func(n int32) bool { return n == 1 }, false, false, 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			tr := transportFunc(func(ctx context.Context, batch Batch) error {
+				if !tt.slow(attempts.Add(1)) {
+					return nil
+				}
+				if tt.ignoresCtx {
+					<-release
+					return nil
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(time.Second):
+					return nil
+				}
+			})
+			e := &Exporter{Transport: tr, RetryLimit: 3, BaseDelay: time.Millisecond, AttemptTimeout: 10 * time.Millisecond}
+			start := time.Now()
+			err := e.SendBatch([]Record{{ID: "a", Payload: "p"}})
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Fatalf("SendBatch took %s, want each attempt cut off after 10ms", elapsed)
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Fatalf("made %d attempts, want %d", got, tt.wantAttempts)
+			}
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("SendBatch = %v, want it to wrap context.DeadlineExceeded", err)
+			}
+		})
+	}
+}
+
+// hookLog returns an Exporter whose hooks record their calls, and the log
+// they write to.
+func hookLog(tr Transport) (*Exporter, *[]string) {
+	var events []string
+	e := &Exporter{
+		Transport:  tr,
+		RetryLimit: 3,
+		BaseDelay:  time.Millisecond,
+		OnAttempt: func(attempt, batchSize int) {
+			events = append(events, fmt.Sprintf("attempt %d size %d", attempt, batchSize))
+		},
+		OnSuccess: func(batchSize int, elapsed time.Duration) {
+			if elapsed <= 0 {
+				events = append(events, "success with no elapsed time")
+			}
+			events = append(events, fmt.Sprintf("success size %d", batchSize))
+		},
+		OnFailure: func(attempt int, err error) {
+			events = append(events, fmt.Sprintf("failure %d: %v", attempt, err))
+		},
+	}
+	return e, &events
+}
+
+func TestHooks(t *testing.T) {
+	busy := RetryableError{Err: errors.New("busy")}
+	for _, tt := range []struct {
+		name string
+		// errs are returned by successive attempts; later ones succeed.
+		errs []error
+		want []string
+	}{
+		{"success", nil, []string{"attempt 1 size 2", "success size 2"}},
+		{
+			"success after retry",
+			[]error{busy},
+			[]string{"attempt 1 size 2", "failure 1: busy", "attempt 2 size 2", "success size 2"},
+		},
+		{
+			"retries exhausted",
+			[]error{busy, busy, busy},
+			[]string{"attempt 1 size 2", "failure 1: busy", "attempt 2 size 2", "failure 2: busy", "attempt 3 size 2", "failure 3: busy"},
+		},
+		{"permanent failure", []error{errors.New("rejected")}, []string{"attempt 1 size 2", "failure 1: rejected"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			attempt := 0
+			e, events := hookLog(transportFunc(func(ctx context.Context, batch Batch) error {
+				attempt++
+				if attempt <= len(tt.errs) {
+					time.Sleep(time.Millisecond)
+					return tt.errs[attempt-1]
+				}
+				return nil
+			}))
+			e.SendBatch([]Record{{ID: "a", Payload: "p"}, {ID: "b", Payload: "p"}})
+			if !reflect.DeepEqual(*events, tt.want) {
+				t.Fatalf("hooks fired %q, want %q", *events, tt.want)
+			}
+		})
+	}
+}
+
+func TestNilHooks(t *testing.T) {
+	tr := transportFunc(func(ctx context.Context, batch Batch) error {
+		return RetryableError{Err: errors.New("busy")}
+	})
+	e := &Exporter{Transport: tr, RetryLimit: 2, BaseDelay: time.Millisecond}
+	if err := e.SendBatch([]Record{{ID: "a", Payload: "p"}}); err == nil {
+		t.Fatal("SendBatch succeeded with a failing transport")
+	}
+}
+
+func TestOnDeadLetter(t *testing.T) {
+	busy := RetryableError{Err: errors.New("busy")}
+	records := []Record{{ID: "a", Payload: "p"}, {ID: "b", Payload: "p"}, {ID: "c", Payload: "p"}}
+	for _, tt := range []struct {
+		name    string
+		split   int
+		fail    map[string]error
+		wantDLQ [][]Record
+	}{
+		{"delivered", 0, nil, nil},
+		{"retries exhausted", 0, map[string]error{"b": busy}, [][]Record{records}},
+		{"permanent failure", 0, map[string]error{"a": errors.New("rejected")}, [][]Record{records}},
+		{"only the failed sub-batch", 1, map[string]error{"b": busy}, [][]Record{records[1:2]}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := transportFunc(func(ctx context.Context, batch Batch) error {
+				for _, record := range batch.Records {
+					if err := tt.fail[record.ID]; err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			var dlq [][]Record
+			var dlqErr error
+			e := &Exporter{
+				Transport:            tr,
+				RetryLimit:           2,
+				BaseDelay:            time.Millisecond,
+				MaxRecordsPerRequest: tt.split,
+				OnDeadLetter: func(records []Record, err error) {
+					dlq = append(dlq, records)
+					dlqErr = err
+				},
+			}
+			err := e.SendBatch(records)
+			if !reflect.DeepEqual(dlq, tt.wantDLQ) {
+				t.Fatalf("dead-lettered %v, want %v", dlq, tt.wantDLQ)
+			}
+			if err != dlqErr {
+				t.Fatalf("SendBatch returned %v, dead letter got %v", err, dlqErr)
+			}
+		})
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	redact := func(r Record) Record { return Record{ID: "redacted", Payload: "***"} }
+	records := []Record{{ID: "user-42", Payload: "secret"}}
+
+	tr := &InMemoryTransport{}
+	e := &Exporter{Transport: tr, Redactor: redact}
+	if err := e.SendBatch(records); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tr.Batches, [][]Record{records}) {
+		t.Fatalf("delivered %v, want the original records", tr.Batches)
+	}
+
+	// Error messages carry the redacted form.
+	e = &Exporter{Transport: tr, Redactor: redact, MaxBatchBytes: 1}
+	err := e.SendBatch(records)
+	if !errors.Is(err, ErrRecordTooLarge) || strings.Contains(err.Error(), "user-42") || !strings.Contains(err.Error(), "redacted") {
+		t.Fatalf("SendBatch = %v, want ErrRecordTooLarge naming the redacted ID", err)
+	}
+
+	// The dead letter gets the originals so they can be replayed.
+	var dlq []Record
+	e = &Exporter{
+		Transport:    transportFunc(func(ctx context.Context, batch Batch) error { return errors.New("rejected") }),
+		Redactor:     redact,
+		OnDeadLetter: func(records []Record, err error) { dlq = records },
+	}
+	e.SendBatch(records)
+	if !reflect.DeepEqual(dlq, records) {
+		t.Fatalf("dead-lettered %v, want the original records", dlq)
+	}
+}
+
+func TestMaxElapsedTime(t *testing.T) {
+	for _, tt := range []struct {
+		name           string
+		retryLimit     int
+		maxElapsed     time.Duration
+		wantAttempts   int
+		wantGaveUp     bool
+		wantMaxElapsed time.Duration
+	}{
+		// Attempts start at 0, 10, 30 and 70ms; the next would start at
+		// 150ms, past the deadline.
+		{"deadline cuts retries short", 100, 110 * time.Millisecond, 4, true, 110 * time.Millisecond},
+		{"retry limit comes first", 2, time.Hour, 2, false, time.Second},
+		{"no deadline", 3, 0, 3, false, time.Second},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			tr := transportFunc(func(ctx context.Context, batch Batch) error {
+				attempts++
+				return RetryableError{Err: errors.New("busy")}
+			})
+			e := &Exporter{Transport: tr, RetryLimit: tt.retryLimit, BaseDelay: 10 * time.Millisecond, MaxElapsedTime: tt.maxElapsed}
+			start := time.Now()
+			err := e.SendBatch([]Record{{ID: "a", Payload: "p"}})
+			elapsed := time.Since(start)
+			if attempts != tt.wantAttempts {
+				t.Fatalf("made %d attempts, want %d", attempts, tt.wantAttempts)
+			}
+			if got := err != nil && strings.Contains(err.Error(), "gave up after"); got != tt.wantGaveUp {
+				t.Fatalf("SendBatch = %v, want gave up %v", err, tt.wantGaveUp)
+			}
+			if elapsed > tt.wantMaxElapsed {
+				t.Fatalf("SendBatch took %s, want at most %s", elapsed, tt.wantMaxElapsed)
+			}
+			var sendErr *BatchSendError
+			if !errors.As(err, &sendErr) || !IsRetryable(sendErr.Err) {
+				t.Fatalf("SendBatch = %v, want the last error wrapped in a BatchSendError", err)
+			}
+		})
+	}
+}
+
+func sequences(batches [][]Record) []uint64 {
+	var seqs []uint64
+	for _, batch := range batches {
+		for _, record := range batch {
+			seqs = append(seqs, record.Sequence)
+		}
+	}
+	return seqs
+}
+
+func TestSendBatchOrderedSequences(t *testing.T) {
+	valid := []Record{{ID: "a", Payload: "p"}, {ID: "b", Payload: "p"}}
+	invalid := []Record{{ID: "c", Payload: "p"}, {ID: "", Payload: "p"}}
+	stale := Record{ID: "old", Payload: "p", Timestamp: time.Now().Add(-time.Hour)}
+	for _, tt := range []struct {
+		name  string
+		e     *Exporter
+		sends [][]Record
+		want  []uint64
+	}{
+		{"contiguous across sends", &Exporter{}, [][]Record{valid, valid[:1], valid}, []uint64{1, 2, 3, 4, 5}},
+		{"split batches keep their numbers", &Exporter{MaxRecordsPerRequest: 1}, [][]Record{valid, valid}, []uint64{1, 2, 3, 4}},
+		{"invalid batch takes no numbers", &Exporter{}, [][]Record{valid, invalid, valid}, []uint64{1, 2, 3, 4}},
+		{
+			"deduplicated records take no numbers",
+			&Exporter{Dedupe: true},
+			[][]Record{{{ID: "a", Payload: "1"}, {ID: "a", Payload: "2"}, {ID: "b", Payload: "p"}}, valid},
+			[]uint64{1, 2, 3, 4},
+		},
+		{"expired records take no numbers", &Exporter{MaxAge: time.Minute}, [][]Record{{stale, valid[0]}, {stale}, valid}, []uint64{1, 2, 3}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &InMemoryTransport{}
+			tt.e.Transport = tr
+			for _, records := range tt.sends {
+				tt.e.SendBatchOrdered(records)
+			}
+			if got := sequences(tr.Batches); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("endpoint saw sequences %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendBatchOrderedLeavesInputUnchanged(t *testing.T) {
+	records := []Record{{ID: "a", Payload: "p"}}
+	if err := (&Exporter{Transport: NoopTransport{}}).SendBatchOrdered(records); err != nil {
+		t.Fatal(err)
+	}
+	if records[0].Sequence != 0 {
+		t.Fatalf("caller's record stamped with %d", records[0].Sequence)
+	}
+}
+
+func TestSendBatchOrderedConcurrent(t *testing.T) {
+	tr := &InMemoryTransport{}
+	e := &Exporter{Transport: tr}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				e.SendBatchOrdered([]Record{{ID: "a", Payload: "p"}, {ID: "b", Payload: "p"}})
+			}
+		}()
+	}
+	wg.Wait()
+	seqs := sequences(tr.Batches)
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	for i, seq := range seqs {
+		if seq != uint64(i+1) {
+			t.Fatalf("sequences %v are not 1 to %d without gaps or repeats", seqs, len(seqs))
+		}
+	}
+	// Each batch holds a contiguous, increasing block.
+	for _, batch := range tr.Batches {
+		if batch[1].Sequence != batch[0].Sequence+1 {
+			t.Fatalf("batch numbered %d, %d", batch[0].Sequence, batch[1].Sequence)
+		}
+	}
+}
+
+func TestSendBatchOrderedDryRunUsesNoNumbers(t *testing.T) {
+	tr := &InMemoryTransport{}
+	e := &Exporter{Transport: tr, DryRun: true}
+	if err := e.SendBatchOrdered([]Record{{ID: "a", Payload: "p"}}); err != nil {
+		t.Fatal(err)
+	}
+	e.DryRun = false
+	if err := e.SendBatchOrdered([]Record{{ID: "b", Payload: "p"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := sequences(tr.Batches); !reflect.DeepEqual(got, []uint64{1}) {
+		t.Fatalf("sequences %v after a dry run, want [1]", got)
+	}
+}
+
+func TestSendBatchN(t *testing.T) {
+	records := []Record{
+		{ID: "a", Payload: "p"}, {ID: "b", Payload: "p"}, {ID: "c", Payload: "p"},
+		{ID: "d", Payload: "p"}, {ID: "e", Payload: "p"},
+	}
+	for _, tt := range []struct {
+		name     string
+		split    int
+		byBytes  bool // split into pairs with MaxBatchBytes instead
+		ordered  bool
+		fail     string
+		wantSent int
+	}{
+		{"single batch delivered", 0, false, false, "", 5},
+		{"single batch fails", 0, false, false, "a", 0},
+		{"last sub-batch fails", 2, false, false, "e", 4},
+		{"middle sub-batch fails", 2, false, false, "c", 3},
+		{"ordered stops at the failure", 2, false, true, "c", 2},
+		{"last byte-limited sub-batch fails", 0, true, false, "e", 4},
+		{"every sub-batch delivered", 2, false, false, "", 5},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := transportFunc(func(ctx context.Context, batch Batch) error {
+				for _, record := range batch.Records {
+					if record.ID == tt.fail {
+						return errors.New("rejected")
+					}
+				}
+				return nil
+			})
+			e := &Exporter{Transport: tr, MaxRecordsPerRequest: tt.split, Ordered: tt.ordered}
+			if tt.byBytes {
+				e.MaxBatchBytes = encodedSize(t, records[:2]...)
+			}
+			sent, err := e.SendBatchN(records)
+			if (err != nil) != (tt.fail != "") {
+				t.Fatalf("SendBatchN error = %v, want failure %v", err, tt.fail != "")
+			}
+			if sent != tt.wantSent {
+				t.Fatalf("sent = %d, want %d", sent, tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestBatchSendError(t *testing.T) {
+	cause := errors.New("rejected")
+	for _, tt := range []struct {
+		name         string
+		err          error
+		split        int
+		wantAttempts int
+		wantSize     int
+	}{
+		{"permanent failure", cause, 0, 1, 3},
+		{"retries exhausted", RetryableError{Err: cause}, 0, 3, 3},
+		{"failed sub-batch", cause, 2, 1, 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := transportFunc(func(ctx context.Context, batch Batch) error {
+				if batch.Records[0].ID == "a" {
+					return tt.err
+				}
+				return nil
+			})
+			e := &Exporter{Transport: tr, RetryLimit: 3, BaseDelay: time.Millisecond, MaxRecordsPerRequest: tt.split}
+			err := e.SendBatch([]Record{{ID: "a", Payload: "p"}, {ID: "b", Payload: "p"}, {ID: "c", Payload: "p"}})
+			var sendErr *BatchSendError
+			if !errors.As(err, &sendErr) {
+				t.Fatalf("SendBatch = %v, want a *BatchSendError", err)
+			}
+			if sendErr.Attempts != tt.wantAttempts || sendErr.BatchSize != tt.wantSize {
+				t.Fatalf("BatchSendError{Attempts: %d, BatchSize: %d}, want {%d, %d}", sendErr.Attempts, sendErr.BatchSize, tt.wantAttempts, tt.wantSize)
+			}
+			if !errors.Is(err, cause) {
+				t.Fatalf("errors.Is(%v, cause) = false", err)
+			}
+			want := fmt.Sprintf("send failed after %d attempts for batch of %d records: ", tt.wantAttempts, tt.wantSize)
+			if !strings.HasPrefix(sendErr.Error(), want) {
+				t.Fatalf("Error() = %q, want prefix %q", sendErr.Error(), want)
+			}
+		})
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	now := time.Now()
+	fresh := Record{ID: "fresh", Payload: "p", Timestamp: now}
+	stale := Record{ID: "stale", Payload: "p", Timestamp: now.Add(-time.Hour)}
+	undated := Record{ID: "undated", Payload: "p"}
+	for _, tt := range []struct {
+		name     string
+		maxAge   time.Duration
+		records  []Record
+		wantSent []Record
+		wantDLQ  []Record
+	}{
+		{"no MaxAge", 0, []Record{fresh, stale, undated}, []Record{fresh, stale, undated}, nil},
+		{"mixed", time.Minute, []Record{stale, fresh, undated, stale}, []Record{fresh, undated}, []Record{stale, stale}},
+		{"zero Timestamp never expires", time.Nanosecond, []Record{undated}, []Record{undated}, nil},
+		{"all stale", time.Minute, []Record{stale}, nil, []Record{stale}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &InMemoryTransport{}
+			var dlq []Record
+			e := &Exporter{
+				Transport: tr,
+				MaxAge:    tt.maxAge,
+				OnDeadLetter: func(records []Record, err error) {
+					if !errors.Is(err, ErrRecordExpired) {
+						t.Errorf("dead letter error %v, want ErrRecordExpired", err)
+					}
+					dlq = append(dlq, records...)
+				},
+			}
+			if err := e.SendBatch(tt.records); err != nil {
+				t.Fatal(err)
+			}
+			var sent []Record
+			for _, batch := range tr.Batches {
+				sent = append(sent, batch...)
+			}
+			if !reflect.DeepEqual(sent, tt.wantSent) {
+				t.Fatalf("sent %v, want %v", sent, tt.wantSent)
+			}
+			if !reflect.DeepEqual(dlq, tt.wantDLQ) {
+				t.Fatalf("dead-lettered %v, want %v", dlq, tt.wantDLQ)
+			}
+			if got := e.Stats().RecordsExpired; got != int64(len(tt.wantDLQ)) {
+				t.Fatalf("RecordsExpired = %d, want %d", got, len(tt.wantDLQ))
+			}
+		})
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		e       *Exporter
+		records []Record
+		wantErr bool
+	}{
+		{"valid batch", &Exporter{}, []Record{{ID: "a", Payload: "p"}, {ID: "b", Payload: "p"}}, false},
+		{"split batch", &Exporter{MaxRecordsPerRequest: 1}, []Record{{ID: "a", Payload: "p"}, {ID: "b", Payload: "p"}}, false},
+		{"invalid record", &Exporter{}, []Record{{ID: "a"}}, true},
+		{"payload too long", &Exporter{MaxPayloadBytes: 1}, []Record{{ID: "a", Payload: "pp"}}, true},
+		{"record too large to encode", &Exporter{MaxBatchBytes: 1}, []Record{{ID: "a", Payload: "p"}}, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &InMemoryTransport{}
+			attempts := 0
+			tt.e.Transport, tt.e.DryRun = tr, true
+			tt.e.OnAttempt = func(int, int) { attempts++ }
+			err := tt.e.SendBatch(tt.records)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SendBatch = %v, want error %v", err, tt.wantErr)
+			}
+			if len(tr.Batches) != 0 {
+				t.Fatalf("dry run delivered %v", tr.Batches)
+			}
+			if !tt.wantErr && attempts == 0 {
+				t.Fatal("dry run did not invoke OnAttempt")
+			}
+			if got := tt.e.Stats(); got != (Stats{}) {
+				t.Fatalf("dry run changed Stats to %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// GroupBySource partitions records by Source, preserving their order within
+// each group. Records without a Source are grouped under the empty key.
+func GroupBySource(records []Record) map[string][]Record {
+	groups := make(map[string][]Record)
+	for _, record := range records {
+		groups[record.Source] = append(groups[record.Source], record)
+	}
+	return groups
+}
+
+// SendGrouped sends each Source's records as separate batches, never mixing
+// sources in one request, via SendAll so BatchSize and Concurrency apply
+// within each group. Groups are sent in order of source name and their
+// failures are joined, each prefixed with its source.
+func (e *Exporter) SendGrouped(records []Record) error {
+	if len(records) == 0 {
+		return errors.New("empty batch")
+	}
+	groups := GroupBySource(records)
+	sources := make([]string, 0, len(groups))
+	for source := range groups {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	var errs []error
+	for _, source := range sources {
+		if err := e.SendAll(groups[source]); err != nil {
+			errs = append(errs, fmt.Errorf("source %q: %w", source, err))
+		}
+	}
+	return errors.Join(errs...)
+}
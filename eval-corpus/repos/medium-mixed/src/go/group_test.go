@@ -0,0 +1,93 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGroupBySource(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		records []Record
+		want    map[string][]Record
+	}{
+		{"no records", nil, map[string][]Record{}},
+		{"one source", []Record{{ID: "a", Source: "s"}, {ID: "b", Source: "s"}}, map[string][]Record{
+			"s": {{ID: "a", Source: "s"}, {ID: "b", Source: "s"}},
+		}},
+		{"interleaved sources keep their order", []Record{{ID: "a", Source: "x"}, {ID: "b", Source: "y"}, {ID: "c", Source: "x"}}, map[string][]Record{
+			"x": {{ID: "a", Source: "x"}, {ID: "c", Source: "x"}},
+			"y": {{ID: "b", Source: "y"}},
+		}},
+		{"empty source", []Record{{ID: "a"}, {ID: "b", Source: "s"}, {ID: "c"}}, map[string][]Record{
+			"":  {{ID: "a"}, {ID: "c"}},
+			"s": {{ID: "b", Source: "s"}},
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GroupBySource(tt.records); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("GroupBySource = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendGrouped(t *testing.T) {
+	var records []Record
+	for _, r := range []struct{ id, source string }{
+		{"a1", "a"}, {"b1", "b"}, {"n1", ""}, {"a2", "a"}, {"a3", "a"},
+		{"a4", "a"}, {"n2", ""}, {"a5", "a"},
+	} {
+		records = append(records, Record{ID: r.id, Payload: "p", Source: r.source})
+	}
+	tr := &InMemoryTransport{}
+	e := &Exporter{Transport: tr, BatchSize: 2}
+	if err := e.SendGrouped(records); err != nil {
+		t.Fatal(err)
+	}
+	// Groups go in source order, each split by BatchSize.
+	want := []string{"n1 n2", "a1 a2", "a3 a4", "a5", "b1"}
+	var got []string
+	for _, batch := range tr.Batches {
+		var ids []string
+		for _, record := range batch {
+			if record.Source != batch[0].Source {
+				t.Fatalf("batch %v mixes sources", batch)
+			}
+			ids = append(ids, record.ID)
+		}
+		got = append(got, strings.Join(ids, " "))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("sent batches %q, want %q", got, want)
+	}
+}
+
+func TestSendGroupedJoinsSourceErrors(t *testing.T) {
+	rejected := errors.New("rejected")
+	tr := transportFunc(func(ctx context.Context, batch Batch) error {
+		if batch.Records[0].Source != "ok" {
+			return rejected
+		}
+		return nil
+	})
+	records := []Record{
+		{ID: "a", Payload: "p", Source: "ok"},
+		{ID: "b", Payload: "p", Source: "bad"},
+		{ID: "c", Payload: "p"},
+	}
+	err := (&Exporter{Transport: tr}).SendGrouped(records)
+	if !errors.Is(err, rejected) {
+		t.Fatalf("SendGrouped = %v, want %v", err, rejected)
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, `source "bad"`) || !strings.Contains(msg, `source ""`) || strings.Contains(msg, `source "ok"`) {
+		t.Fatalf("SendGrouped error %q should name exactly the failed sources", msg)
+	}
+	if err := (&Exporter{Transport: tr}).SendGrouped(nil); err == nil {
+		t.Fatal("SendGrouped(nil) succeeded, want empty batch error")
+	}
+}
@@ -0,0 +1,32 @@
+package exporter
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+)
+
+// IdempotencyKey derives a key for a batch from its records' contents. The
+// key ignores record order, so it is the same for every retry of a batch and
+// for any reordering of it, but changes whenever a record is added, removed
+// or altered.
+func IdempotencyKey(records []Record) string {
+	entries := make([]string, len(records))
+	for i, record := range records {
+		entries[i] = string(appendField(appendField(appendField(nil, record.ID), record.Payload), record.Source))
+	}
+	sort.Strings(entries)
+	h := sha256.New()
+	for _, entry := range entries {
+		h.Write([]byte(entry))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// appendField length-prefixes s so field boundaries cannot be forged by the
+// field contents.
+func appendField(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
@@ -0,0 +1,59 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	a := Record{ID: "a", Payload: "1"}
+	b := Record{ID: "b", Payload: "2", Source: "s"}
+	for _, tt := range []struct {
+		name  string
+		x, y  []Record
+		equal bool
+	}{
+		{"same records", []Record{a, b}, []Record{a, b}, true},
+		{"reordered", []Record{a, b}, []Record{b, a}, true},
+		{"record added", []Record{a}, []Record{a, b}, false},
+		{"record removed", []Record{a, b}, []Record{b}, false},
+		{"payload changed", []Record{a}, []Record{{ID: "a", Payload: "2"}}, false},
+		{"source changed", []Record{b}, []Record{{ID: "b", Payload: "2"}}, false},
+		{"field boundary moved", []Record{{ID: "ab", Payload: "c"}}, []Record{{ID: "a", Payload: "bc"}}, false},
+		{"duplicate record", []Record{a}, []Record{a, a}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			x, y := IdempotencyKey(tt.x), IdempotencyKey(tt.y)
+			if (x == y) != tt.equal {
+				t.Fatalf("keys %s and %s, want equal %v", x, y, tt.equal)
+			}
+		})
+	}
+}
+
+func TestIdempotencyKeyReusedAcrossRetries(t *testing.T) {
+	var keys []string
+	tr := transportFunc(func(ctx context.Context, batch Batch) error {
+		keys = append(keys, batch.IdempotencyKey)
+		if len(keys) < 3 {
+			return RetryableError{Err: errors.New("response lost")}
+		}
+		return nil
+	})
+	records := []Record{{ID: "a", Payload: "p"}, {ID: "b", Payload: "p"}}
+	e := &Exporter{Transport: tr, RetryLimit: 3, BaseDelay: time.Millisecond}
+	if err := e.SendBatch(records); err != nil {
+		t.Fatal(err)
+	}
+	want := IdempotencyKey(records)
+	for i, key := range keys {
+		if key != want {
+			t.Fatalf("attempt %d sent key %q, want %q", i+1, key, want)
+		}
+	}
+	if len(keys) != 3 {
+		t.Fatalf("made %d attempts, want 3", len(keys))
+	}
+}
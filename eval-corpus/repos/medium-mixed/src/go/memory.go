@@ -0,0 +1,27 @@
+package exporter
+
+import (
+	"context"
+	"sync"
+)
+
+// NoopTransport accepts every batch without sending it anywhere.
+type NoopTransport struct{}
+
+func (NoopTransport) Deliver(ctx context.Context, batch Batch) error { return nil }
+
+// InMemoryTransport records the records of every delivered batch, in
+// delivery order, for later inspection. It is safe for concurrent use; read
+// Batches only once sends have finished.
+type InMemoryTransport struct {
+	mu      sync.Mutex
+	Batches [][]Record
+}
+
+func (t *InMemoryTransport) Deliver(ctx context.Context, batch Batch) error {
+	records := append([]Record(nil), batch.Records...)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Batches = append(t.Batches, records)
+	return nil
+}
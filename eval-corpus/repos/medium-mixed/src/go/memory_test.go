@@ -0,0 +1,59 @@
+package exporter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func ExampleNoopTransport() {
+	e := &Exporter{Transport: NoopTransport{}}
+	err := e.SendBatch([]Record{{ID: "a", Payload: "p"}})
+	fmt.Println(err, e.Stats().RecordsSent)
+	// Output: <nil> 1
+}
+
+func ExampleInMemoryTransport() {
+	tr := &InMemoryTransport{}
+	e := &Exporter{Transport: tr, MaxRecordsPerRequest: 2}
+	if err := e.SendBatch([]Record{{ID: "a", Payload: "p"}, {ID: "b", Payload: "p"}, {ID: "c", Payload: "p"}}); err != nil {
+		fmt.Println(err)
+	}
+	for _, batch := range tr.Batches {
+		fmt.Println(len(batch), batch[0].ID)
+	}
+	// Output:
+	// 2 a
+	// 1 c
+}
+
+func TestInMemoryTransportConcurrentDeliver(t *testing.T) {
+	tr := &InMemoryTransport{}
+	e := &Exporter{Transport: tr}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				e.SendBatch([]Record{{ID: "a", Payload: "p"}})
+			}
+		}()
+	}
+	wg.Wait()
+	if len(tr.Batches) != 200 {
+		t.Fatalf("recorded %d batches, want 200", len(tr.Batches))
+	}
+}
+
+func TestInMemoryTransportCopiesRecords(t *testing.T) {
+	tr := &InMemoryTransport{}
+	records := []Record{{ID: "a", Payload: "p"}}
+	if err := (&Exporter{Transport: tr}).SendBatch(records); err != nil {
+		t.Fatal(err)
+	}
+	records[0].ID = "changed"
+	if got := tr.Batches[0][0].ID; got != "a" {
+		t.Fatalf("recorded ID %q after the caller reused its slice, want a", got)
+	}
+}
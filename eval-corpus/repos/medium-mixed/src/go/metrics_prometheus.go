@@ -0,0 +1,68 @@
+//go:build prometheus
+
+package exporter
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterMetrics registers counters for batches sent, records sent and
+// retries and a histogram of send durations with reg, and wires e's hooks
+// to update them. Hooks already set keep firing. Sends under DryRun are not
+// counted. If a collector cannot be registered, those registered before it
+// are unregistered again and e is left unchanged. Call it before the first
+// send; it is only built with the prometheus build tag.
+func (e *Exporter) RegisterMetrics(reg prometheus.Registerer) error {
+	batches := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "exporter",
+		Name:      "batches_sent_total",
+		Help:      "Batches delivered successfully.",
+	})
+	records := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "exporter",
+		Name:      "records_sent_total",
+		Help:      "Records delivered successfully.",
+	})
+	retries := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "exporter",
+		Name:      "retries_total",
+		Help:      "Delivery attempts after the first for a batch.",
+	})
+	duration := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "exporter",
+		Name:      "send_duration_seconds",
+		Help:      "Time to deliver a batch across all of its attempts.",
+		Buckets:   prometheus.DefBuckets,
+	})
+	collectors := []prometheus.Collector{batches, records, retries, duration}
+	for i, c := range collectors {
+		if err := reg.Register(c); err != nil {
+			for _, registered := range collectors[:i] {
+				reg.Unregister(registered)
+			}
+			return err
+		}
+	}
+	onAttempt, onSuccess := e.OnAttempt, e.OnSuccess
+	e.OnAttempt = func(attempt int, batchSize int) {
+		if attempt > 1 && !e.DryRun {
+			retries.Inc()
+		}
+		if onAttempt != nil {
+			onAttempt(attempt, batchSize)
+		}
+	}
+	e.OnSuccess = func(batchSize int, elapsed time.Duration) {
+		if !e.DryRun {
+			batches.Inc()
+			records.Add(float64(batchSize))
+			duration.Observe(elapsed.Seconds())
+		}
+		if onSuccess != nil {
+			onSuccess(batchSize, elapsed)
+		}
+	}
+	return nil
+}
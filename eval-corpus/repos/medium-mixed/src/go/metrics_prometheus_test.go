@@ -0,0 +1,97 @@
+//go:build prometheus
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const sentMetrics = `
+# HELP exporter_batches_sent_total Batches delivered successfully.
+# TYPE exporter_batches_sent_total counter
+exporter_batches_sent_total %d
+# HELP exporter_records_sent_total Records delivered successfully.
+# TYPE exporter_records_sent_total counter
+exporter_records_sent_total %d
+# HELP exporter_retries_total Delivery attempts after the first for a batch.
+# TYPE exporter_retries_total counter
+exporter_retries_total %d
+`
+
+func TestRegisterMetrics(t *testing.T) {
+	failures := 0
+	tr := transportFunc(func(ctx context.Context, batch Batch) error {
+		if batch.Records[0].ID == "flaky" {
+			if failures++; failures == 1 {
+				return RetryableError{Err: errors.New("busy")}
+			}
+		}
+		return nil
+	})
+	var userHooks int
+	e := &Exporter{Transport: tr, RetryLimit: 3, BaseDelay: time.Millisecond, OnSuccess: func(int, time.Duration) { userHooks++ }}
+	reg := prometheus.NewPedanticRegistry()
+	if err := e.RegisterMetrics(reg); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name                   string
+		dryRun                 bool
+		records                []Record
+		batches, recs, retries int
+	}{
+		{"delivered", false, []Record{{ID: "a", Payload: "p"}, {ID: "b", Payload: "p"}}, 1, 2, 0},
+		{"retried", false, []Record{{ID: "flaky", Payload: "p"}}, 2, 3, 1},
+		{"dry run is not counted", true, []Record{{ID: "c", Payload: "p"}}, 2, 3, 1},
+	} {
+		e.DryRun = tt.dryRun
+		if err := e.SendBatch(tt.records); err != nil {
+			t.Fatal(err)
+		}
+		want := strings.NewReader(fmt.Sprintf(sentMetrics, tt.batches, tt.recs, tt.retries))
+		if err := testutil.GatherAndCompare(reg, want, "exporter_batches_sent_total", "exporter_records_sent_total", "exporter_retries_total"); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+	}
+	if n, err := testutil.GatherAndCount(reg, "exporter_send_duration_seconds"); err != nil || n != 1 {
+		t.Fatalf("send duration histogram: %d series, err %v", n, err)
+	}
+	if userHooks != 3 {
+		t.Fatalf("existing OnSuccess fired %d times, want 3 including the dry run", userHooks)
+	}
+}
+
+func TestRegisterMetricsUnregistersOnError(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	// A collector already holding the retries name makes the third
+	// registration fail.
+	taken := prometheus.NewCounter(prometheus.CounterOpts{Namespace: "exporter", Name: "retries_total", Help: "Taken."})
+	reg.MustRegister(taken)
+	e := &Exporter{Transport: NoopTransport{}}
+	if err := e.RegisterMetrics(reg); err == nil {
+		t.Fatal("RegisterMetrics succeeded with a name already registered")
+	}
+	if e.OnAttempt != nil || e.OnSuccess != nil {
+		t.Fatal("RegisterMetrics set hooks despite failing")
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(families) != 1 || families[0].GetName() != "exporter_retries_total" {
+		t.Fatalf("registry holds %d metric families after the failure, want only the one registered before", len(families))
+	}
+	// Once the clash is gone the metrics register cleanly.
+	reg.Unregister(taken)
+	if err := e.RegisterMetrics(reg); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,61 @@
+package exporter
+
+import (
+	"fmt"
+	"time"
+)
+
+// Option configures an Exporter built by NewExporter.
+type Option func(*Exporter)
+
+// NewExporter returns an Exporter posting to endpoint with a RetryLimit of 3
+// and a BatchSize of 100, adjusted by opts. Options panic on values that
+// would leave the Exporter unusable.
+func NewExporter(endpoint string, opts ...Option) *Exporter {
+	e := &Exporter{
+		Endpoint:   endpoint,
+		BatchSize:  100,
+		RetryLimit: 3,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// WithBatchSize sets BatchSize, which must be at least 1.
+func WithBatchSize(n int) Option {
+	if n < 1 {
+		panic(fmt.Sprintf("exporter: WithBatchSize(%d): batch size must be at least 1", n))
+	}
+	return func(e *Exporter) { e.BatchSize = n }
+}
+
+// WithRetryLimit sets RetryLimit, which must be at least 1: it counts the
+// first attempt as well as retries.
+func WithRetryLimit(n int) Option {
+	if n < 1 {
+		panic(fmt.Sprintf("exporter: WithRetryLimit(%d): retry limit must be at least 1", n))
+	}
+	return func(e *Exporter) { e.RetryLimit = n }
+}
+
+// WithBackoff sets BaseDelay and MaxDelay. base must be positive and max,
+// unless zero for uncapped, no smaller than base.
+func WithBackoff(base, max time.Duration) Option {
+	if base <= 0 || max < 0 || (max > 0 && max < base) {
+		panic(fmt.Sprintf("exporter: WithBackoff(%s, %s): need 0 < base <= max, or max of 0", base, max))
+	}
+	return func(e *Exporter) {
+		e.BaseDelay = base
+		e.MaxDelay = max
+	}
+}
+
+// WithTransport sets Transport, which must not be nil.
+func WithTransport(t Transport) Option {
+	if t == nil {
+		panic("exporter: WithTransport(nil)")
+	}
+	return func(e *Exporter) { e.Transport = t }
+}
@@ -0,0 +1,59 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewExporter(t *testing.T) {
+	tr := &InMemoryTransport{}
+	for _, tt := range []struct {
+		name string
+		opts []Option
+		want func(e *Exporter) bool
+	}{
+		{"defaults", nil, func(e *Exporter) bool {
+			return e.Endpoint == "http://collector" && e.BatchSize == 100 && e.RetryLimit == 3 && e.Transport == nil && e.BaseDelay == 0
+		}},
+		{"batch size", []Option{WithBatchSize(7)}, func(e *Exporter) bool { return e.BatchSize == 7 && e.RetryLimit == 3 }},
+		{"retry limit", []Option{WithRetryLimit(1)}, func(e *Exporter) bool { return e.RetryLimit == 1 && e.BatchSize == 100 }},
+		{"backoff", []Option{WithBackoff(time.Millisecond, time.Second)}, func(e *Exporter) bool {
+			return e.BaseDelay == time.Millisecond && e.MaxDelay == time.Second
+		}},
+		{"uncapped backoff", []Option{WithBackoff(time.Millisecond, 0)}, func(e *Exporter) bool { return e.MaxDelay == 0 }},
+		{"transport", []Option{WithTransport(tr)}, func(e *Exporter) bool { return e.Transport == tr }},
+		{"later option wins", []Option{WithBatchSize(5), WithBatchSize(9)}, func(e *Exporter) bool { return e.BatchSize == 9 }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if e := NewExporter("http://collector", tt.opts...); !tt.want(e) {
+				t.Fatalf("NewExporter gave %+v", e)
+			}
+		})
+	}
+}
+
+func TestOptionsPanicOnMisuse(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opt  func() Option
+		want string
+	}{
+		{"zero batch size", func() Option { return WithBatchSize(0) }, "batch size must be at least 1"},
+		{"negative retry limit", func() Option { return WithRetryLimit(-1) }, "retry limit must be at least 1"},
+		{"zero base delay", func() Option { return WithBackoff(0, time.Second) }, "WithBackoff"},
+		{"max below base", func() Option { return WithBackoff(time.Second, time.Millisecond) }, "WithBackoff"},
+		{"negative max", func() Option { return WithBackoff(time.Second, -1) }, "WithBackoff"},
+		{"nil transport", func() Option { return WithTransport(nil) }, "WithTransport(nil)"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				msg, _ := recover().(string)
+				if !strings.Contains(msg, tt.want) {
+					t.Fatalf("panicked with %q, want it to mention %q", msg, tt.want)
+				}
+			}()
+			tt.opt()
+		})
+	}
+}
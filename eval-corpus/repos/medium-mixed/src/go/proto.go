@@ -0,0 +1,136 @@
+package exporter
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ProtoEncoder encodes a batch in protobuf wire format without depending on
+// a protobuf runtime. The body matches these messages:
+//
+//	message Record {
+//	  string id = 1;
+//	  string payload = 2;
+//	  string source = 3;
+//	  uint64 sequence = 4;
+//	  int64 timestamp_unix_nano = 5;
+//	}
+//	message RecordBatch {
+//	  repeated Record records = 1;
+//	}
+type ProtoEncoder struct{}
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (ProtoEncoder) Encode(records []Record) ([]byte, string, error) {
+	var body, msg []byte
+	for _, record := range records {
+		msg = msg[:0]
+		msg = appendProtoString(msg, 1, record.ID)
+		msg = appendProtoString(msg, 2, record.Payload)
+		msg = appendProtoString(msg, 3, record.Source)
+		if record.Sequence != 0 {
+			msg = binary.AppendUvarint(msg, 4<<3|wireVarint)
+			msg = binary.AppendUvarint(msg, record.Sequence)
+		}
+		if !record.Timestamp.IsZero() {
+			msg = binary.AppendUvarint(msg, 5<<3|wireVarint)
+			msg = binary.AppendUvarint(msg, uint64(record.Timestamp.UnixNano()))
+		}
+		body = binary.AppendUvarint(body, 1<<3|wireBytes)
+		body = binary.AppendUvarint(body, uint64(len(msg)))
+		body = append(body, msg...)
+	}
+	return body, "application/x-protobuf", nil
+}
+
+// Decode parses a body produced by Encode, skipping unknown fields.
+func (ProtoEncoder) Decode(body []byte) ([]Record, error) {
+	var records []Record
+	err := walkProto(body, func(field uint64, varint uint64, data []byte) error {
+		if field != 1 || data == nil {
+			return nil
+		}
+		var record Record
+		err := walkProto(data, func(field uint64, varint uint64, data []byte) error {
+			switch field {
+			case 1:
+				record.ID = string(data)
+			case 2:
+				record.Payload = string(data)
+			case 3:
+				record.Source = string(data)
+			case 4:
+				record.Sequence = varint
+			case 5:
+				record.Timestamp = time.Unix(0, int64(varint))
+			}
+			return nil
+		})
+		records = append(records, record)
+		return err
+	})
+	return records, err
+}
+
+func appendProtoString(b []byte, field uint64, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = binary.AppendUvarint(b, field<<3|wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+var errProtoTruncated = errors.New("protobuf: truncated message")
+
+// walkProto calls fn for each field of a message. Length-delimited fields
+// pass their contents as data; varint fields pass their value with nil data.
+func walkProto(b []byte, fn func(field uint64, varint uint64, data []byte) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errProtoTruncated
+		}
+		b = b[n:]
+		field, wire := tag>>3, tag&7
+		switch wire {
+		case wireVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return errProtoTruncated
+			}
+			b = b[n:]
+			if err := fn(field, v, nil); err != nil {
+				return err
+			}
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return errProtoTruncated
+			}
+			data := b[n : n+int(size)]
+			b = b[n+int(size):]
+			if err := fn(field, 0, data); err != nil {
+				return err
+			}
+		case 1, 5:
+			width := 8
+			if wire == 5 {
+				width = 4
+			}
+			if len(b) < width {
+				return errProtoTruncated
+			}
+			b = b[width:]
+		default:
+			return fmt.Errorf("protobuf: unsupported wire type %d", wire)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package exporter
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// sameRecords compares records field by field, comparing timestamps as
+// instants since a decoded Timestamp comes back in the local time zone.
+func sameRecords(got, want []Record) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		g, w := got[i], want[i]
+		if !g.Timestamp.Equal(w.Timestamp) {
+			return false
+		}
+		g.Timestamp, w.Timestamp = time.Time{}, time.Time{}
+		if g != w {
+			return false
+		}
+	}
+	return true
+}
+
+func TestProtoEncoderRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		records []Record
+	}{
+		{"empty batch", nil},
+		{"one record", []Record{{ID: "a", Payload: "p"}}},
+		{"every field", []Record{{ID: "a", Payload: "p", Source: "s", Sequence: 1 << 40, Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 7, time.UTC)}}},
+		{"negative timestamp", []Record{{ID: "a", Timestamp: time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC)}}},
+		{"empty record", []Record{{}}},
+		{"unicode and binary", []Record{{ID: "é", Payload: "\x00\xff日本"}, {ID: "b"}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType, err := ProtoEncoder{}.Encode(tt.records)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if contentType != "application/x-protobuf" {
+				t.Fatalf("content type %q, want application/x-protobuf", contentType)
+			}
+			got, err := ProtoEncoder{}.Decode(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sameRecords(got, tt.records) {
+				t.Fatalf("round trip gave %+v, want %+v", got, tt.records)
+			}
+		})
+	}
+}
+
+func TestProtoDecode(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		body    []byte
+		want    []Record
+		wantErr error
+	}{
+		// Field 9 (varint) and field 10 (fixed64) are unknown and skipped.
+		{"unknown fields", []byte{0x0a, 0x03, 0x0a, 0x01, 'a', 0x48, 0x01, 0x51, 0, 0, 0, 0, 0, 0, 0, 0}, []Record{{ID: "a"}}, nil},
+		{"truncated length", []byte{0x0a, 0x05, 0x0a}, nil, errProtoTruncated},
+		{"truncated tag", []byte{0x80}, nil, errProtoTruncated},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProtoEncoder{}.Decode(tt.body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decode error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !sameRecords(got, tt.want) {
+				t.Fatalf("Decode = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
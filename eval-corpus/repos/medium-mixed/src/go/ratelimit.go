@@ -0,0 +1,75 @@
+package exporter
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// tokenBucket paces callers to rate tokens per second, allowing up to burst
+// tokens to be taken back to back after an idle period.
+type tokenBucket struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// wait takes a token, blocking until one is available or ctx is done. A
+// non-positive rate never blocks.
+func (b *tokenBucket) wait(ctx context.Context, rate float64, burst int) error {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	b.mu.Lock()
+	b.refill(rate, burst)
+	// Taking the token up front reserves a place in line; the debt is
+	// repaid by waiting for the bucket to refill.
+	b.tokens--
+	delay := time.Duration(-b.tokens / rate * float64(time.Second))
+	b.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	if err := sleep(ctx, delay); err != nil {
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// take takes a token if one is available without waiting. A non-positive
+// rate always succeeds.
+func (b *tokenBucket) take(rate float64, burst int) bool {
+	if rate <= 0 {
+		return true
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(rate, burst)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// refill credits the tokens earned since the last call, starting full. The
+// caller holds b.mu.
+func (b *tokenBucket) refill(rate float64, burst int) {
+	now := time.Now()
+	if b.last.IsZero() {
+		b.tokens = float64(burst)
+	} else {
+		b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	}
+	b.last = now
+}
@@ -0,0 +1,114 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimitPacesSends(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		rate     float64
+		sends    int
+		min, max time.Duration
+	}{
+		// The first send takes the bucket's token; each later one waits
+		// 1/rate for the next.
+		{"50 per second", 50, 6, 90 * time.Millisecond, 400 * time.Millisecond},
+		{"100 per second", 100, 11, 90 * time.Millisecond, 400 * time.Millisecond},
+		{"unlimited", 0, 100, 0, 100 * time.Millisecond},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Exporter{Transport: NoopTransport{}, RateLimit: tt.rate}
+			start := time.Now()
+			for i := 0; i < tt.sends; i++ {
+				if err := e.SendBatch([]Record{{ID: "a", Payload: "p"}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if elapsed := time.Since(start); elapsed < tt.min || elapsed > tt.max {
+				t.Fatalf("%d sends took %s, want between %s and %s", tt.sends, elapsed, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestRateLimitWaitIsCancellable(t *testing.T) {
+	e := &Exporter{Transport: NoopTransport{}, RateLimit: 0.5}
+	records := []Record{{ID: "a", Payload: "p"}}
+	if err := e.SendBatch(records); err != nil {
+		t.Fatal(err)
+	}
+	// The next token is two seconds away.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := e.SendBatchContext(ctx, records)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("SendBatchContext = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("cancelled wait took %s", elapsed)
+	}
+}
+
+func TestRetryBudgetSharedAcrossSends(t *testing.T) {
+	var attempts atomic.Int64
+	tr := transportFunc(func(ctx context.Context, batch Batch) error {
+		attempts.Add(1)
+		return RetryableError{Err: errors.New("busy")}
+	})
+	// A burst of 5 and a refill of one retry a second leaves the 8 batches
+	// 5 retries between them, not the 8*9 RetryLimit would allow.
+	e := &Exporter{Transport: tr, RetryLimit: 10, BaseDelay: time.Millisecond, RetryBudget: 1, RetryBudgetBurst: 5}
+	var wg sync.WaitGroup
+	var exhausted atomic.Int64
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := e.SendBatch([]Record{{ID: "a", Payload: "p"}}); errors.Is(err, ErrRetryBudgetExhausted) {
+				exhausted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	retries := e.Stats().RetryCount
+	if retries > 6 {
+		t.Fatalf("%d retries across concurrent sends, want at most the burst of 5 plus one refill", retries)
+	}
+	if got := attempts.Load(); got != 8+retries {
+		t.Fatalf("made %d attempts, want 8 first attempts plus %d retries", got, retries)
+	}
+	if exhausted.Load() != 8 {
+		t.Fatalf("%d of 8 batches failed with ErrRetryBudgetExhausted, want all", exhausted.Load())
+	}
+}
+
+func TestRetryBudgetRefills(t *testing.T) {
+	failures := 0
+	tr := transportFunc(func(ctx context.Context, batch Batch) error {
+		if failures++; failures%2 == 1 {
+			return RetryableError{Err: errors.New("busy")}
+		}
+		return nil
+	})
+	// Each send fails once and needs one retry; the budget holds one.
+	e := &Exporter{Transport: tr, RetryLimit: 3, BaseDelay: time.Millisecond, RetryBudget: 20}
+	records := []Record{{ID: "a", Payload: "p"}}
+	if err := e.SendBatch(records); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SendBatch(records); !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Fatalf("second send = %v, want ErrRetryBudgetExhausted", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	failures = 0
+	if err := e.SendBatch(records); err != nil {
+		t.Fatalf("send after refill = %v, want success", err)
+	}
+}
@@ -0,0 +1,195 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RecordTransport is implemented by transports whose endpoint accepts or
+// rejects each record of a batch on its own, letting SendBatchResilient
+// retry only the records that failed.
+type RecordTransport interface {
+	Transport
+	// DeliverRecords returns one error per record of batch, in order; a nil
+	// error means the record was accepted.
+	DeliverRecords(ctx context.Context, batch Batch) []error
+}
+
+// SendBatchResilient sends records and retries only those that fail
+// retryably, up to RetryLimit attempts in all. Transports that are not a
+// RecordTransport succeed or fail a batch as a whole. Once more than
+// FailureRatio of the records cannot be delivered, retrying stops and the
+// batch fails; below that, the results report which records failed and the
+// error is nil. Records are validated but never deduplicated or expired, so
+// results line up with records. Attempts go through the same RateLimit,
+// circuit breaker, RetryBudget and MaxElapsedTime checks as SendBatch.
+func (e *Exporter) SendBatchResilient(records []Record) ([]RecordResult, error) {
+	return e.SendBatchResilientContext(context.Background(), records)
+}
+
+// SendBatchResilientContext is SendBatchResilient with cancellation: a done
+// ctx aborts the retry loop, including any backoff currently in progress.
+func (e *Exporter) SendBatchResilientContext(ctx context.Context, records []Record) ([]RecordResult, error) {
+	if len(records) == 0 {
+		return nil, errors.New("empty batch")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := e.ValidateRecords(records); err != nil {
+		return nil, err
+	}
+	if e.DryRun {
+		batches, err := e.prepare(records)
+		if err != nil {
+			return nil, err
+		}
+		for _, batch := range batches {
+			e.dryRun(batch)
+		}
+		results := make([]RecordResult, len(records))
+		for i, record := range records {
+			results[i].Record = record
+		}
+		return results, nil
+	}
+	started := time.Now()
+	tolerated := e.FailureRatio * float64(len(records))
+	results := make([]RecordResult, len(records))
+	pending := make([]int, len(records))
+	for i, record := range records {
+		results[i].Record = record
+		pending[i] = i
+	}
+	transport := e.transport()
+	permanent := 0
+	try := func(ctx context.Context) (int, error) {
+		batch := make([]Record, len(pending))
+		for j, i := range pending {
+			batch[j] = records[i]
+		}
+		errs, err := e.attemptRecords(ctx, transport, batch)
+		if err != nil {
+			return 0, err
+		}
+		delivered := 0
+		var retry []int
+		var retryErr, permanentErr error
+		for j, i := range pending {
+			results[i].Err = errs[j]
+			switch {
+			case errs[j] == nil:
+				delivered++
+			case IsRetryable(errs[j]):
+				retry = append(retry, i)
+				if retryErr == nil {
+					retryErr = errs[j]
+				}
+			default:
+				permanent++
+				if permanentErr == nil {
+					permanentErr = errs[j]
+				}
+			}
+		}
+		pending = retry
+		switch {
+		case float64(permanent) > tolerated:
+			// Permanent failures alone already decide the outcome.
+			pending = nil
+			return delivered, permanentErr
+		case len(retry) > 0:
+			return delivered, RetryableError{Err: fmt.Errorf("%d of %d records failed: %w", len(retry), len(batch), retryErr)}
+		}
+		return delivered, nil
+	}
+
+	err := e.breaker.allow(e.ConsecutiveFailureThreshold, e.CircuitResetTimeout)
+	if err != nil {
+		for i := range results {
+			results[i].Err = err
+		}
+	} else {
+		err = e.retryAttempts(ctx, started, func() int { return len(pending) }, try)
+		for _, i := range pending {
+			// Records never attempted take the error that stopped the loop.
+			if results[i].Err == nil {
+				results[i].Err = err
+			}
+		}
+		if ctx.Err() == nil {
+			err = e.tolerate(results, tolerated, err)
+		}
+		e.breaker.record(e.ConsecutiveFailureThreshold, err, ctx.Err() != nil)
+	}
+	if err != nil {
+		e.stats.failures.Add(1)
+		if e.OnDeadLetter != nil {
+			e.OnDeadLetter(FailedRecords(results), err)
+		}
+	}
+	return results, err
+}
+
+// tolerate decides the outcome of a resilient send from its results: nil
+// while the failed records stay within FailureRatio, otherwise an error
+// wrapping cause, or the first record error when cause is nil.
+func (e *Exporter) tolerate(results []RecordResult, tolerated float64, cause error) error {
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			if cause == nil {
+				cause = result.Err
+			}
+		}
+	}
+	if failed == 0 || float64(failed) <= tolerated {
+		return nil
+	}
+	return fmt.Errorf("%d of %d records failed, more than FailureRatio %g allows: %w", failed, len(results), e.FailureRatio, cause)
+}
+
+// attemptRecords makes one delivery attempt of records, prepared as for
+// SendBatch, and returns each record's outcome. Each sub-batch is delivered
+// under AttemptTimeout as attempt does; when it times out, every record in
+// it gets the timeout error. The error reports records that could not be
+// prepared at all.
+func (e *Exporter) attemptRecords(ctx context.Context, transport Transport, records []Record) ([]error, error) {
+	batches, err := e.prepare(records)
+	if err != nil {
+		return nil, err
+	}
+	errs := make([]error, 0, len(records))
+	for _, batch := range batches {
+		rt, ok := transport.(RecordTransport)
+		if !ok {
+			err := e.attempt(ctx, transport, batch)
+			for range batch.Records {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		// batchErrs is only read once the attempt has returned nil, so an
+		// abandoned call writing it late is never observed.
+		var batchErrs []error
+		err := e.withAttemptTimeout(ctx, func(ctx context.Context) error {
+			results := rt.DeliverRecords(ctx, batch)
+			if len(results) != len(batch.Records) {
+				return fmt.Errorf("transport returned %d results for %d records", len(results), len(batch.Records))
+			}
+			batchErrs = results
+			return nil
+		})
+		if err != nil {
+			for range batch.Records {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		errs = append(errs, batchErrs...)
+	}
+	return errs, nil
+}
@@ -0,0 +1,162 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// subsetTransport is a RecordTransport that fails chosen records: those in
+// busy retryably for their first busy[id] attempts, those in rejected
+// permanently. It counts the attempts each record gets.
+type subsetTransport struct {
+	mu       sync.Mutex
+	busy     map[string]int
+	rejected map[string]bool
+	attempts map[string]int
+}
+
+func (s *subsetTransport) Deliver(ctx context.Context, batch Batch) error {
+	return errors.Join(s.DeliverRecords(ctx, batch)...)
+}
+
+func (s *subsetTransport) DeliverRecords(ctx context.Context, batch Batch) []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attempts == nil {
+		s.attempts = make(map[string]int)
+	}
+	errs := make([]error, len(batch.Records))
+	for i, record := range batch.Records {
+		s.attempts[record.ID]++
+		switch {
+		case s.rejected[record.ID]:
+			errs[i] = errors.New("rejected " + record.ID)
+		case s.attempts[record.ID] <= s.busy[record.ID]:
+			errs[i] = RetryableError{Err: errors.New("busy " + record.ID)}
+		}
+	}
+	return errs
+}
+
+func TestSendBatchResilient(t *testing.T) {
+	records := []Record{{ID: "a", Payload: "p"}, {ID: "b", Payload: "p"}, {ID: "c", Payload: "p"}, {ID: "d", Payload: "p"}}
+	for _, tt := range []struct {
+		name         string
+		busy         map[string]int
+		rejected     map[string]bool
+		ratio        float64
+		wantErr      bool
+		wantFailed   string
+		wantAttempts map[string]int
+	}{
+		{"all delivered", nil, nil, 0, false, "", map[string]int{"a": 1, "b": 1, "c": 1, "d": 1}},
+		{"only the busy records are retried", map[string]int{"b": 1, "d": 2}, nil, 0, false, "", map[string]int{"a": 1, "b": 2, "c": 1, "d": 3}},
+		{"retries run out", map[string]int{"c": 5}, nil, 0, true, "c", map[string]int{"a": 1, "b": 1, "c": 3, "d": 1}},
+		{"rejected record within FailureRatio", nil, map[string]bool{"a": true}, 0.25, false, "a", map[string]int{"a": 1, "b": 1, "c": 1, "d": 1}},
+		{"rejected records over FailureRatio", nil, map[string]bool{"a": true, "b": true}, 0.25, true, "ab", map[string]int{"a": 1, "b": 1, "c": 1, "d": 1}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &subsetTransport{busy: tt.busy, rejected: tt.rejected}
+			var dlq string
+			e := &Exporter{
+				Transport:    tr,
+				RetryLimit:   3,
+				BaseDelay:    time.Millisecond,
+				FailureRatio: tt.ratio,
+				OnDeadLetter: func(records []Record, err error) {
+					for _, record := range records {
+						dlq += record.ID
+					}
+				},
+			}
+			results, err := e.SendBatchResilient(records)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SendBatchResilient = %v, want error %v", err, tt.wantErr)
+			}
+			var failed string
+			for _, record := range FailedRecords(results) {
+				failed += record.ID
+			}
+			if failed != tt.wantFailed {
+				t.Fatalf("failed records %q, want %q", failed, tt.wantFailed)
+			}
+			for id, want := range tt.wantAttempts {
+				if got := tr.attempts[id]; got != want {
+					t.Fatalf("record %s got %d attempts, want %d", id, got, want)
+				}
+			}
+			// Only a failed send reaches the dead letter.
+			wantDLQ := ""
+			if tt.wantErr {
+				wantDLQ = tt.wantFailed
+			}
+			if dlq != wantDLQ {
+				t.Fatalf("dead-lettered %q, want %q", dlq, wantDLQ)
+			}
+		})
+	}
+}
+
+func TestSendBatchResilientWholeBatchTransport(t *testing.T) {
+	attempts := 0
+	tr := transportFunc(func(ctx context.Context, batch Batch) error {
+		if attempts++; attempts == 1 {
+			return RetryableError{Err: errors.New("busy")}
+		}
+		return nil
+	})
+	e := &Exporter{Transport: tr, RetryLimit: 3, BaseDelay: time.Millisecond}
+	results, err := e.SendBatchResilient([]Record{{ID: "a", Payload: "p"}, {ID: "b", Payload: "p"}})
+	if err != nil || len(FailedRecords(results)) != 0 || attempts != 2 {
+		t.Fatalf("SendBatchResilient = %v with %d attempts, want the whole batch retried once", err, attempts)
+	}
+}
+
+// stuckRecords is a RecordTransport whose DeliverRecords ignores its
+// context and blocks until release is closed.
+type stuckRecords struct{ release chan struct{} }
+
+func (s stuckRecords) Deliver(ctx context.Context, batch Batch) error { return nil }
+
+func (s stuckRecords) DeliverRecords(ctx context.Context, batch Batch) []error {
+	<-s.release
+	return make([]error, len(batch.Records))
+}
+
+func TestSendBatchResilientAttemptTimeout(t *testing.T) {
+	tr := stuckRecords{release: make(chan struct{})}
+	defer close(tr.release)
+	e := &Exporter{Transport: tr, RetryLimit: 2, BaseDelay: time.Millisecond, AttemptTimeout: 20 * time.Millisecond}
+	start := time.Now()
+	results, err := e.SendBatchResilient([]Record{{ID: "a", Payload: "p"}, {ID: "b", Payload: "p"}})
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("SendBatchResilient waited %s on a stuck transport", elapsed)
+	}
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("SendBatchResilient = %v, want the attempt timeout", err)
+	}
+	if len(FailedRecords(results)) != 2 {
+		t.Fatalf("results %v, want both records failed", results)
+	}
+}
+
+// shortRecords returns one result too few.
+type shortRecords struct{}
+
+func (shortRecords) Deliver(ctx context.Context, batch Batch) error { return nil }
+
+func (shortRecords) DeliverRecords(ctx context.Context, batch Batch) []error {
+	return make([]error, len(batch.Records)-1)
+}
+
+func TestSendBatchResilientResultCountMismatch(t *testing.T) {
+	e := &Exporter{Transport: shortRecords{}, RetryLimit: 3}
+	_, err := e.SendBatchResilient([]Record{{ID: "a", Payload: "p"}, {ID: "b", Payload: "p"}})
+	if err == nil || !strings.Contains(err.Error(), "transport returned 1 results for 2 records") {
+		t.Fatalf("SendBatchResilient = %v, want the result count mismatch", err)
+	}
+}
@@ -0,0 +1,110 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// SendAll chunks records into batches of BatchSize and sends them with up to
+// Concurrency batches in flight. Each batch gets its own RetryLimit; failures
+// are combined into one error reporting how many batches failed. With
+// Ordered set, batches are instead sent one after another and the first
+// failure stops the rest.
+func (e *Exporter) SendAll(records []Record) error {
+	return e.SendAllContext(context.Background(), records)
+}
+
+// SendAllContext is SendAll with cancellation applied to every batch.
+func (e *Exporter) SendAllContext(ctx context.Context, records []Record) error {
+	return e.sendAll(ctx, records, nil)
+}
+
+// sendAll is SendAllContext that, when results is non-nil, fills in each
+// record's outcome as send does.
+func (e *Exporter) sendAll(ctx context.Context, records []Record, results []RecordResult) error {
+	if len(records) == 0 {
+		return errors.New("empty batch")
+	}
+	chunks := chunk(records, e.BatchSize)
+	// parts[i] receives the results of chunks[i].
+	parts := make([][]RecordResult, len(chunks))
+	if results != nil {
+		offset := 0
+		for i := range chunks {
+			parts[i] = results[offset : offset+len(chunks[i])]
+			offset += len(chunks[i])
+		}
+	}
+	if e.Ordered {
+		return e.sendOrdered(ctx, chunks, parts)
+	}
+	workers := e.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(chunks) {
+		workers = len(chunks)
+	}
+	errs := make([]error, len(chunks))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				_, errs[i] = e.send(ctx, chunks[i], parts[i], false)
+			}
+		}()
+	}
+	for i := range chunks {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	var failed []error
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, fmt.Errorf("batch %d: %w", i+1, err))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d batches failed: %w", len(failed), len(chunks), errors.Join(failed...))
+}
+
+// sendOrdered sends chunks one at a time, stopping at the first batch that
+// cannot be delivered. Records of the batches left unsent get its error.
+func (e *Exporter) sendOrdered(ctx context.Context, chunks [][]Record, parts [][]RecordResult) error {
+	for i, records := range chunks {
+		if _, err := e.send(ctx, records, parts[i], false); err != nil {
+			for j := i + 1; j < len(chunks); j++ {
+				for k := range parts[j] {
+					parts[j][k] = RecordResult{Record: chunks[j][k], Err: err}
+				}
+			}
+			return fmt.Errorf("batch %d of %d failed, %d not sent: %w", i+1, len(chunks), len(chunks)-i-1, err)
+		}
+	}
+	return nil
+}
+
+// chunk splits records into consecutive slices of at most size records.
+// A non-positive size yields a single chunk.
+func chunk(records []Record, size int) [][]Record {
+	if size <= 0 || size >= len(records) {
+		return [][]Record{records}
+	}
+	chunks := make([][]Record, 0, (len(records)+size-1)/size)
+	for start := 0; start < len(records); start += size {
+		end := start + size
+		if end > len(records) {
+			end = len(records)
+		}
+		chunks = append(chunks, records[start:end])
+	}
+	return chunks
+}
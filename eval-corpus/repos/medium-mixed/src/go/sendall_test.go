@@ -0,0 +1,144 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChunk(t *testing.T) {
+	records := []Record{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}, {ID: "e"}}
+	for _, tt := range []struct {
+		size int
+		want []int
+	}{
+		{0, []int{5}},
+		{-1, []int{5}},
+		{2, []int{2, 2, 1}},
+		{5, []int{5}},
+		{9, []int{5}},
+	} {
+		var got []int
+		for _, c := range chunk(records, tt.size) {
+			got = append(got, len(c))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("chunk(5 records, %d) sizes = %v, want %v", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestSendAllOrdered(t *testing.T) {
+	mem := &InMemoryTransport{}
+	var inFlight, maxInFlight atomic.Int32
+	tr := transportFunc(func(ctx context.Context, batch Batch) error {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		if n > maxInFlight.Load() {
+			maxInFlight.Store(n)
+		}
+		time.Sleep(time.Millisecond)
+		return mem.Deliver(ctx, batch)
+	})
+	// Ordered overrides Concurrency.
+	e := &Exporter{Transport: tr, BatchSize: 2, Concurrency: 4, Ordered: true}
+	if err := e.SendAll([]Record{
+		{ID: "a", Payload: "p"}, {ID: "b", Payload: "p"}, {ID: "c", Payload: "p"},
+		{ID: "d", Payload: "p"}, {ID: "e", Payload: "p"}, {ID: "f", Payload: "p"}, {ID: "g", Payload: "p"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, batch := range mem.Batches {
+		var ids string
+		for _, record := range batch {
+			ids += record.ID
+		}
+		got = append(got, ids)
+	}
+	if want := []string{"ab", "cd", "ef", "g"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("received batches %q, want %q", got, want)
+	}
+	if maxInFlight.Load() != 1 {
+		t.Fatalf("%d batches were in flight at once, want 1", maxInFlight.Load())
+	}
+}
+
+func TestSendAllOrderedStopsAtFailure(t *testing.T) {
+	rejected := errors.New("rejected")
+	var attempted []string
+	tr := transportFunc(func(ctx context.Context, batch Batch) error {
+		attempted = append(attempted, batch.Records[0].ID)
+		if batch.Records[0].ID == "c" {
+			return rejected
+		}
+		return nil
+	})
+	records := []Record{
+		{ID: "a", Payload: "p"}, {ID: "b", Payload: "p"}, {ID: "c", Payload: "p"},
+		{ID: "d", Payload: "p"}, {ID: "e", Payload: "p"}, {ID: "f", Payload: "p"},
+		{ID: "g", Payload: "p"},
+	}
+	for _, tt := range []struct {
+		name          string
+		ordered       bool
+		wantAttempted []string
+		wantErr       string
+	}{
+		{"ordered", true, []string{"a", "c"}, "batch 2 of 4 failed, 2 not sent"},
+		{"unordered carries on", false, []string{"a", "c", "e", "g"}, "1 of 4 batches failed"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			attempted = nil
+			e := &Exporter{Transport: tr, BatchSize: 2, Ordered: tt.ordered}
+			err := e.SendAll(records)
+			if !errors.Is(err, rejected) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("SendAll = %v, want %q wrapping %v", err, tt.wantErr, rejected)
+			}
+			if !reflect.DeepEqual(attempted, tt.wantAttempted) {
+				t.Fatalf("attempted batches led by %v, want %v", attempted, tt.wantAttempted)
+			}
+		})
+	}
+}
+
+func TestMaxRecordsPerRequest(t *testing.T) {
+	records := make([]Record, 10)
+	for i := range records {
+		records[i] = Record{ID: string(rune('a' + i)), Payload: "p"}
+	}
+	for _, tt := range []struct {
+		name      string
+		batchSize int
+		max       int
+		wantSizes []int
+	}{
+		{"no cap", 10, 0, []int{10}},
+		{"cap splits each batch", 10, 3, []int{3, 3, 3, 1}},
+		{"cap applies within each BatchSize chunk", 6, 4, []int{4, 2, 4}},
+		{"cap above BatchSize", 5, 8, []int{5, 5}},
+		{"cap of one", 10, 1, []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &InMemoryTransport{}
+			e := &Exporter{Transport: tr, BatchSize: tt.batchSize, MaxRecordsPerRequest: tt.max}
+			if err := e.SendAll(records); err != nil {
+				t.Fatal(err)
+			}
+			var sizes []int
+			for _, batch := range tr.Batches {
+				sizes = append(sizes, len(batch))
+			}
+			if !reflect.DeepEqual(sizes, tt.wantSizes) {
+				t.Fatalf("sub-request sizes %v, want %v", sizes, tt.wantSizes)
+			}
+			if got := sentIDs(tr); got != "abcdefghij" {
+				t.Fatalf("delivered %q, want every record once in order", got)
+			}
+		})
+	}
+}
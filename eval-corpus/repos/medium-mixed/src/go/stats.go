@@ -0,0 +1,43 @@
+package exporter
+
+import "sync/atomic"
+
+// Stats is a snapshot of an Exporter's cumulative counters.
+type Stats struct {
+	RecordsSent  int64
+	BatchesSent  int64
+	RetryCount   int64
+	FailureCount int64
+	// RecordsExpired counts records dropped for exceeding MaxAge.
+	RecordsExpired int64
+}
+
+type counters struct {
+	recordsSent atomic.Int64
+	batchesSent atomic.Int64
+	retries     atomic.Int64
+	failures    atomic.Int64
+	expired     atomic.Int64
+}
+
+// Stats returns the counters accumulated since the Exporter was created or
+// last Reset. It is safe to call while sends are in flight.
+func (e *Exporter) Stats() Stats {
+	return Stats{
+		RecordsSent:    e.stats.recordsSent.Load(),
+		BatchesSent:    e.stats.batchesSent.Load(),
+		RetryCount:     e.stats.retries.Load(),
+		FailureCount:   e.stats.failures.Load(),
+		RecordsExpired: e.stats.expired.Load(),
+	}
+}
+
+// Reset zeroes the counters. Sends in flight may land on either side of the
+// reset.
+func (e *Exporter) Reset() {
+	e.stats.recordsSent.Store(0)
+	e.stats.batchesSent.Store(0)
+	e.stats.retries.Store(0)
+	e.stats.failures.Store(0)
+	e.stats.expired.Store(0)
+}
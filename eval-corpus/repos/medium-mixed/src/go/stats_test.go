@@ -0,0 +1,72 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	tr := transportFunc(func(ctx context.Context, batch Batch) error {
+		mu.Lock()
+		defer mu.Unlock()
+		id := batch.Records[0].ID
+		seen[id]++
+		switch {
+		case id == "bad":
+			return errors.New("rejected")
+		case id == "busy", id == "flaky" && seen[id] == 1:
+			return RetryableError{Err: errors.New("busy")}
+		}
+		return nil
+	})
+	e := &Exporter{Transport: tr, RetryLimit: 2, BaseDelay: time.Millisecond}
+	// Each step sends a batch led by id and checks the running totals.
+	for _, tt := range []struct {
+		id   string
+		size int
+		want Stats
+	}{
+		{"ok", 2, Stats{RecordsSent: 2, BatchesSent: 1}},
+		{"flaky", 1, Stats{RecordsSent: 3, BatchesSent: 2, RetryCount: 1}},
+		{"bad", 3, Stats{RecordsSent: 3, BatchesSent: 2, RetryCount: 1, FailureCount: 1}},
+		{"busy", 1, Stats{RecordsSent: 3, BatchesSent: 2, RetryCount: 2, FailureCount: 2}},
+		{"ok", 4, Stats{RecordsSent: 7, BatchesSent: 3, RetryCount: 2, FailureCount: 2}},
+	} {
+		records := []Record{{ID: tt.id, Payload: "p"}}
+		for i := 1; i < tt.size; i++ {
+			records = append(records, Record{ID: tt.id + string(rune('0'+i)), Payload: "p"})
+		}
+		e.SendBatch(records)
+		if got := e.Stats(); got != tt.want {
+			t.Fatalf("after sending %s: Stats = %+v, want %+v", tt.id, got, tt.want)
+		}
+	}
+	e.Reset()
+	if got := e.Stats(); got != (Stats{}) {
+		t.Fatalf("Stats after Reset = %+v, want zero", got)
+	}
+}
+
+func TestStatsWhileSending(t *testing.T) {
+	e := &Exporter{Transport: NoopTransport{}}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				e.SendBatch([]Record{{ID: "a", Payload: "p"}, {ID: "b", Payload: "p"}})
+				e.Stats()
+			}
+		}()
+	}
+	wg.Wait()
+	if got, want := e.Stats(), (Stats{RecordsSent: 800, BatchesSent: 400}); got != want {
+		t.Fatalf("Stats = %+v, want %+v", got, want)
+	}
+}
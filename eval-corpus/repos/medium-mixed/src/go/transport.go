@@ -0,0 +1,234 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Batch is the payload of one delivery: the records and their encoded
+// request body. ContentEncoding is set when Body is compressed, along with
+// UncompressedChecksum, the BatchChecksum of the body before compression.
+// IdempotencyKey stays the same across every retry of the batch.
+type Batch struct {
+	Records              []Record
+	Body                 []byte
+	ContentType          string
+	ContentEncoding      string
+	UncompressedChecksum string
+	IdempotencyKey       string
+}
+
+// BatchChecksum returns the hex SHA-256 digest of body, as sent by
+// HTTPTransport for the endpoint to verify the body arrived intact.
+func BatchChecksum(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Transport delivers a batch to its destination. Implementations wrap
+// transient failures in RetryableError; any other error ends the retry loop
+// immediately.
+type Transport interface {
+	Deliver(ctx context.Context, batch Batch) error
+}
+
+// RetryableError marks a delivery failure that may succeed on a later attempt.
+type RetryableError struct {
+	Err error
+}
+
+func (r RetryableError) Error() string { return r.Err.Error() }
+
+func (r RetryableError) Unwrap() error { return r.Err }
+
+// ErrMissingEndpoint is returned when no Endpoint is configured. It is never
+// retryable.
+var ErrMissingEndpoint = errors.New("missing endpoint")
+
+// IsRetryable reports whether err, or any error it wraps, is a
+// RetryableError, *RetryableError or an *HTTPError with a retryable status.
+func IsRetryable(err error) bool {
+	var r RetryableError
+	var p *RetryableError
+	var h *HTTPError
+	switch {
+	case errors.As(err, &r), errors.As(err, &p):
+		return true
+	case errors.As(err, &h):
+		return h.Retryable()
+	}
+	return false
+}
+
+// HTTPError is a non-2xx response from the endpoint. RetryAfter holds the
+// response's Retry-After delay, if it sent one.
+type HTTPError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (h *HTTPError) Error() string {
+	return fmt.Sprintf("unexpected status %d %s", h.StatusCode, http.StatusText(h.StatusCode))
+}
+
+// Retryable reports whether the status is one a later attempt may succeed
+// on: 429 Too Many Requests or a 500, 502, 503 or 504 server error.
+func (h *HTTPError) Retryable() bool {
+	switch h.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the Retry-After delay carried by err, or zero.
+func retryAfter(err error) time.Duration {
+	var h *HTTPError
+	if errors.As(err, &h) {
+		return h.RetryAfter
+	}
+	return 0
+}
+
+// parseRetryAfter reads a Retry-After header given either as delay seconds
+// or as an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// Pinger is implemented by transports that can check their destination is
+// reachable without delivering records. Exporter.Ping uses it.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// HTTPTransport POSTs each batch to Endpoint. Network errors are retryable;
+// non-2xx responses are returned as *HTTPError. Each request carries the
+// BatchChecksum of its body in X-Content-SHA256 and, when compressed, that
+// of the uncompressed body in X-Uncompressed-Content-SHA256.
+type HTTPTransport struct {
+	Endpoint string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+	// Headers are set on every request. Content-Type, Content-Encoding,
+	// Idempotency-Key and the checksum headers are set by the transport and
+	// take precedence.
+	Headers map[string]string
+}
+
+// WithBearerToken returns a copy of t that authenticates every request with
+// token. An empty token leaves the Authorization header unset.
+func (t HTTPTransport) WithBearerToken(token string) HTTPTransport {
+	if token == "" {
+		return t
+	}
+	headers := make(map[string]string, len(t.Headers)+1)
+	for k, v := range t.Headers {
+		headers[k] = v
+	}
+	headers["Authorization"] = "Bearer " + token
+	t.Headers = headers
+	return t
+}
+
+func (t HTTPTransport) Deliver(ctx context.Context, batch Batch) error {
+	if t.Endpoint == "" {
+		return ErrMissingEndpoint
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.Endpoint, bytes.NewReader(batch.Body))
+	if err != nil {
+		return err
+	}
+	for k, v := range t.Headers {
+		req.Header.Set(k, v)
+	}
+	contentType := batch.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	req.Header.Set("Content-Type", contentType)
+	if batch.ContentEncoding != "" {
+		req.Header.Set("Content-Encoding", batch.ContentEncoding)
+	}
+	req.Header.Set("X-Content-SHA256", BatchChecksum(batch.Body))
+	if batch.UncompressedChecksum != "" {
+		req.Header.Set("X-Uncompressed-Content-SHA256", batch.UncompressedChecksum)
+	}
+	if batch.IdempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", batch.IdempotencyKey)
+	}
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return RetryableError{Err: err}
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// Ping sends a HEAD request to Endpoint. Any response below 500 counts as
+// reachable, since endpoints commonly reject HEAD on a POST route.
+func (t HTTPTransport) Ping(ctx context.Context) error {
+	if t.Endpoint == "" {
+		return ErrMissingEndpoint
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, t.Endpoint, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range t.Headers {
+		req.Header.Set(k, v)
+	}
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return RetryableError{Err: err}
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return &HTTPError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	return nil
+}
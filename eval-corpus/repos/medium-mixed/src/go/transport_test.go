@@ -0,0 +1,310 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsRetryable(t *testing.T) {
+	transient := errors.New("connection reset")
+	for _, tt := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain error", transient, false},
+		{"missing endpoint", ErrMissingEndpoint, false},
+		{"RetryableError", RetryableError{Err: transient}, true},
+		{"*RetryableError", &RetryableError{Err: transient}, true},
+		{"wrapped RetryableError", fmt.Errorf("deliver: %w", RetryableError{Err: transient}), true},
+		{"retryable status", &HTTPError{StatusCode: 503}, true},
+		{"permanent status", &HTTPError{StatusCode: 400}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Fatalf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryStopsOnNonRetryableError(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		err          error
+		wantAttempts int
+	}{
+		{"missing endpoint", ErrMissingEndpoint, 1},
+		{"plain error", errors.New("rejected"), 1},
+		{"retryable error", RetryableError{Err: errors.New("busy")}, 3},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			tr := transportFunc(func(ctx context.Context, batch Batch) error {
+				attempts++
+				return tt.err
+			})
+			e := &Exporter{Transport: tr, RetryLimit: 3, BaseDelay: time.Millisecond}
+			err := e.SendBatch([]Record{{ID: "a", Payload: "p"}})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("SendBatch = %v, want %v", err, tt.err)
+			}
+			if attempts != tt.wantAttempts {
+				t.Fatalf("made %d attempts, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestHTTPTransportMissingEndpoint(t *testing.T) {
+	attempts := 0
+	e := &Exporter{RetryLimit: 3, OnAttempt: func(int, int) { attempts++ }}
+	if err := e.SendBatch([]Record{{ID: "a", Payload: "p"}}); !errors.Is(err, ErrMissingEndpoint) {
+		t.Fatalf("SendBatch = %v, want ErrMissingEndpoint", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("made %d attempts, want 1", attempts)
+	}
+}
+
+func TestHTTPTransportStatusCodes(t *testing.T) {
+	for _, tt := range []struct {
+		status       int
+		wantAttempts int
+	}{
+		{http.StatusOK, 1},
+		{http.StatusAccepted, 1},
+		{http.StatusTooManyRequests, 3},
+		{http.StatusInternalServerError, 3},
+		{http.StatusBadGateway, 3},
+		{http.StatusServiceUnavailable, 3},
+		{http.StatusGatewayTimeout, 3},
+		{http.StatusBadRequest, 1},
+		{http.StatusUnauthorized, 1},
+		{http.StatusForbidden, 1},
+		{http.StatusNotFound, 1},
+	} {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			attempts := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+			e := &Exporter{Endpoint: srv.URL, RetryLimit: 3, BaseDelay: time.Millisecond}
+			err := e.SendBatch([]Record{{ID: "a", Payload: "p"}})
+			var h *HTTPError
+			switch {
+			case tt.status < 300 && err != nil:
+				t.Fatalf("SendBatch = %v, want success", err)
+			case tt.status >= 300 && (!errors.As(err, &h) || h.StatusCode != tt.status):
+				t.Fatalf("SendBatch = %v, want an *HTTPError with status %d", err, tt.status)
+			}
+			if attempts != tt.wantAttempts {
+				t.Fatalf("made %d attempts, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		header   string
+		maxDelay time.Duration
+		min, max time.Duration
+	}{
+		// The 1ms BaseDelay would retry at once; Retry-After holds it off.
+		{"honored", "1", 0, time.Second, 3 * time.Second},
+		{"capped at MaxDelay", "60", 50 * time.Millisecond, 50 * time.Millisecond, time.Second},
+		{"absent", "", 0, 0, 500 * time.Millisecond},
+		{"unparsable", "soon", 0, 0, 500 * time.Millisecond},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if attempts++; attempts == 1 {
+					if tt.header != "" {
+						w.Header().Set("Retry-After", tt.header)
+					}
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer srv.Close()
+			e := &Exporter{Endpoint: srv.URL, RetryLimit: 2, BaseDelay: time.Millisecond, MaxDelay: tt.maxDelay}
+			start := time.Now()
+			if err := e.SendBatch([]Record{{ID: "a", Payload: "p"}}); err != nil {
+				t.Fatal(err)
+			}
+			if elapsed := time.Since(start); elapsed < tt.min || elapsed > tt.max {
+				t.Fatalf("retry took %s, want between %s and %s", elapsed, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestRetryAfterCountsTowardsMaxElapsedTime(t *testing.T) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	e := &Exporter{Endpoint: srv.URL, RetryLimit: 3, BaseDelay: time.Millisecond, MaxElapsedTime: time.Second}
+	err := e.SendBatch([]Record{{ID: "a", Payload: "p"}})
+	if err == nil || !strings.Contains(err.Error(), "gave up") {
+		t.Fatalf("SendBatch = %v, want it to give up rather than wait a minute", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("made %d attempts, want 1", attempts)
+	}
+}
+
+func TestHTTPTransportHeaders(t *testing.T) {
+	base := HTTPTransport{Headers: map[string]string{"X-Route": "eu", "X-Tenant": "acme", "Content-Type": "text/plain"}}
+	for _, tt := range []struct {
+		name     string
+		token    string
+		wantAuth string
+	}{
+		{"with token", "s3cret", "Bearer s3cret"},
+		{"empty token", "", ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var got http.Header
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Clone()
+			}))
+			defer srv.Close()
+			tr := base
+			tr.Endpoint = srv.URL
+			tr = tr.WithBearerToken(tt.token)
+			if err := (&Exporter{Transport: tr}).SendBatch([]Record{{ID: "a", Payload: "p"}}); err != nil {
+				t.Fatal(err)
+			}
+			if got.Get("X-Route") != "eu" || got.Get("X-Tenant") != "acme" {
+				t.Fatalf("custom headers missing from %v", got)
+			}
+			if ct := got.Get("Content-Type"); ct != "application/json" {
+				t.Fatalf("Content-Type = %q, want the transport's own application/json", ct)
+			}
+			if auth, ok := got["Authorization"]; ok != (tt.wantAuth != "") || got.Get("Authorization") != tt.wantAuth {
+				t.Fatalf("Authorization = %q, want %q", auth, tt.wantAuth)
+			}
+		})
+	}
+	if _, ok := base.Headers["Authorization"]; ok {
+		t.Fatal("WithBearerToken modified the original Headers map")
+	}
+}
+
+func TestPing(t *testing.T) {
+	var methods []string
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		// Endpoints commonly refuse HEAD on their POST route.
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	defer live.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	for _, tt := range []struct {
+		name    string
+		e       *Exporter
+		wantErr bool
+	}{
+		{"live server", &Exporter{Endpoint: live.URL}, false},
+		{"server error", &Exporter{Endpoint: failing.URL}, true},
+		{"closed server", &Exporter{Endpoint: closed.URL}, true},
+		{"transport without Ping", &Exporter{Transport: NoopTransport{}}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.e.Ping(context.Background()); (err != nil) != tt.wantErr {
+				t.Fatalf("Ping = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+	if len(methods) != 1 || methods[0] != http.MethodHead {
+		t.Fatalf("live server got %v, want a single HEAD", methods)
+	}
+	if err := (&Exporter{}).Ping(context.Background()); !errors.Is(err, ErrMissingEndpoint) {
+		t.Fatalf("Ping without endpoint = %v, want ErrMissingEndpoint", err)
+	}
+}
+
+func TestBatchChecksum(t *testing.T) {
+	for body, want := range map[string]string{
+		"":    "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		"abc": "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+	} {
+		if got := BatchChecksum([]byte(body)); got != want {
+			t.Errorf("BatchChecksum(%q) = %s, want %s", body, got, want)
+		}
+	}
+}
+
+func TestHTTPTransportChecksumHeaders(t *testing.T) {
+	records := []Record{{ID: "a", Payload: strings.Repeat("compressible ", 200)}}
+	for _, tt := range []struct {
+		name     string
+		compress bool
+	}{
+		{"uncompressed", false},
+		{"compressed", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var header http.Header
+			var body []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header.Clone()
+				body, _ = io.ReadAll(r.Body)
+			}))
+			defer srv.Close()
+			e := &Exporter{Endpoint: srv.URL, Compress: tt.compress}
+			if err := e.SendBatch(records); err != nil {
+				t.Fatal(err)
+			}
+			// The checksum covers the exact bytes on the wire.
+			if got, want := header.Get("X-Content-SHA256"), BatchChecksum(body); got != want {
+				t.Fatalf("X-Content-SHA256 = %q, want %q", got, want)
+			}
+			uncompressed := header.Get("X-Uncompressed-Content-SHA256")
+			if !tt.compress {
+				if uncompressed != "" {
+					t.Fatalf("uncompressed request carries X-Uncompressed-Content-SHA256 %q", uncompressed)
+				}
+				return
+			}
+			if want := BatchChecksum(gunzip(t, body)); uncompressed != want {
+				t.Fatalf("X-Uncompressed-Content-SHA256 = %q, want %q", uncompressed, want)
+			}
+			if uncompressed == header.Get("X-Content-SHA256") {
+				t.Fatal("compressed and uncompressed checksums are equal")
+			}
+		})
+	}
+}
+
+func TestChecksumDetectsTruncation(t *testing.T) {
+	body, err := (&Exporter{}).Marshal([]Record{{ID: "a", Payload: "p"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if BatchChecksum(body) == BatchChecksum(body[:len(body)-1]) {
+		t.Fatal("truncated body has the same checksum")
+	}
+}
@@ -0,0 +1,34 @@
+package exporter
+
+import "fmt"
+
+// ValidationError describes the first invalid record in a batch.
+type ValidationError struct {
+	Index  int
+	Field  string
+	Reason string
+}
+
+func (v *ValidationError) Error() string {
+	return fmt.Sprintf("invalid record %d: %s %s", v.Index, v.Field, v.Reason)
+}
+
+// ValidateRecords checks that every record has an ID and a payload no larger
+// than MaxPayloadBytes, returning a *ValidationError for the first violation.
+func (e *Exporter) ValidateRecords(records []Record) error {
+	for i, record := range records {
+		switch {
+		case record.ID == "":
+			return &ValidationError{Index: i, Field: "ID", Reason: "is empty"}
+		case record.Payload == "":
+			return &ValidationError{Index: i, Field: "Payload", Reason: "is empty"}
+		case e.MaxPayloadBytes > 0 && len(record.Payload) > e.MaxPayloadBytes:
+			return &ValidationError{
+				Index:  i,
+				Field:  "Payload",
+				Reason: fmt.Sprintf("is %d bytes, limit %d", len(record.Payload), e.MaxPayloadBytes),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package exporter
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// recordLog is the write-ahead log behind a durable AsyncExporter. Each
+// record is framed as a 4-byte big-endian length followed by its JSON
+// encoding. Appends are not synced, so records added since the last reset
+// survive a process crash but not necessarily a power loss; reset syncs the
+// new contents and the directory entry before returning.
+type recordLog struct {
+	path string
+	f    *os.File
+	size int64
+}
+
+// openRecordLog opens or creates the log at path and returns the records
+// it holds. A torn or corrupt tail, as left by a crash mid-write, is
+// truncated back to the last complete record.
+func openRecordLog(path string) (*recordLog, []Record, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, err
+	}
+	records, valid := decodeLog(data)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	l := &recordLog{path: path, f: f, size: int64(valid)}
+	if err := l.rewind(); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return l, records, nil
+}
+
+func decodeLog(data []byte) ([]Record, int) {
+	var records []Record
+	off := 0
+	for len(data)-off >= 4 {
+		n := int(binary.BigEndian.Uint32(data[off:]))
+		if n > len(data)-off-4 {
+			break
+		}
+		var record Record
+		if err := json.Unmarshal(data[off+4:off+4+n], &record); err != nil {
+			break
+		}
+		records = append(records, record)
+		off += 4 + n
+	}
+	return records, off
+}
+
+func appendFrame(b []byte, record Record) ([]byte, error) {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	b = binary.BigEndian.AppendUint32(b, uint32(len(body)))
+	return append(b, body...), nil
+}
+
+// append writes record to the end of the log. A failed write is rolled
+// back so later appends do not follow a torn frame.
+func (l *recordLog) append(record Record) error {
+	frame, err := appendFrame(nil, record)
+	if err != nil {
+		return err
+	}
+	if _, err := l.f.Write(frame); err != nil {
+		return errors.Join(err, l.rewind())
+	}
+	l.size += int64(len(frame))
+	return nil
+}
+
+// rewind truncates the file to the last complete frame.
+func (l *recordLog) rewind() error {
+	if err := l.f.Truncate(l.size); err != nil {
+		return err
+	}
+	_, err := l.f.Seek(l.size, io.SeekStart)
+	return err
+}
+
+// reset replaces the log's contents with records, atomically via a rename.
+func (l *recordLog) reset(records []Record) error {
+	var data []byte
+	for _, record := range records {
+		var err error
+		if data, err = appendFrame(data, record); err != nil {
+			return err
+		}
+	}
+	tmp := l.path + ".tmp"
+	if err := writeSynced(tmp, data); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return err
+	}
+	if err := syncDir(filepath.Dir(l.path)); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	l.f.Close()
+	l.f = f
+	l.size = int64(len(data))
+	_, err = f.Seek(l.size, io.SeekStart)
+	return err
+}
+
+// writeSynced writes data to a new file at path and syncs it to disk, so a
+// rename over the log never exposes a file whose contents are not yet
+// durable.
+func writeSynced(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir syncs dir so a rename within it is durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func (l *recordLog) close() error {
+	return l.f.Close()
+}
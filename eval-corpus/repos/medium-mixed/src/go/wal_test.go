@@ -0,0 +1,296 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// rejectTransport fails every delivery permanently.
+type rejectTransport struct{}
+
+func (rejectTransport) Deliver(ctx context.Context, batch Batch) error {
+	return errors.New("rejected")
+}
+
+// rejectIDs records delivered batches but fails any batch holding one of
+// its IDs.
+type rejectIDs struct {
+	InMemoryTransport
+	ids map[string]bool
+}
+
+func (r *rejectIDs) Deliver(ctx context.Context, batch Batch) error {
+	for _, record := range batch.Records {
+		if r.ids[record.ID] {
+			return errors.New("rejected " + record.ID)
+		}
+	}
+	return r.InMemoryTransport.Deliver(ctx, batch)
+}
+
+// outageTransport records delivered batches but fails every delivery while
+// down is set.
+type outageTransport struct {
+	InMemoryTransport
+	down atomic.Bool
+}
+
+func (o *outageTransport) Deliver(ctx context.Context, batch Batch) error {
+	if o.down.Load() {
+		return errors.New("endpoint down")
+	}
+	return o.InMemoryTransport.Deliver(ctx, batch)
+}
+
+// waitSent waits for tr to have delivered want.
+func waitSent(t *testing.T, tr *InMemoryTransport, want string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		tr.mu.Lock()
+		got := sentIDs(tr)
+		tr.mu.Unlock()
+		if got == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("sent %q, want %q", got, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func logIDs(t *testing.T, path string) string {
+	t.Helper()
+	log, records, err := openRecordLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.close()
+	var ids string
+	for _, record := range records {
+		ids += record.ID
+	}
+	return ids
+}
+
+func sentIDs(tr *InMemoryTransport) string {
+	var ids string
+	for _, batch := range tr.Batches {
+		for _, record := range batch {
+			ids += record.ID
+		}
+	}
+	return ids
+}
+
+func TestDurableAsyncExporterReplaysAfterCrash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.log")
+	// A crash leaves records in the log without any flush having run.
+	log, _, err := openRecordLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		if err := log.append(Record{ID: id, Payload: "p"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	log.close()
+
+	tr := &InMemoryTransport{}
+	a, err := NewDurableAsyncExporter(&Exporter{Transport: tr}, path, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Add(Record{ID: "d", Payload: "p"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := sentIDs(tr); got != "abcd" {
+		t.Fatalf("sent %q, want replayed records ahead of new ones", got)
+	}
+	if got := logIDs(t, path); got != "" {
+		t.Fatalf("log holds %q after delivery, want empty", got)
+	}
+}
+
+func TestDurableAsyncExporterKeepsFailedRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.log")
+	a, err := NewDurableAsyncExporter(&Exporter{Transport: rejectTransport{}}, path, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := a.Add(Record{ID: id, Payload: "p"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Close(); err == nil {
+		t.Fatal("Close succeeded with a rejecting transport")
+	}
+	if got := logIDs(t, path); got != "ab" {
+		t.Fatalf("log holds %q, want undelivered records kept", got)
+	}
+
+	tr := &InMemoryTransport{}
+	a, err = NewDurableAsyncExporter(&Exporter{Transport: tr}, path, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := sentIDs(tr); got != "ab" {
+		t.Fatalf("replayed %q, want ab", got)
+	}
+}
+
+func TestDurableAsyncExporterDropsOnlyDeliveredBatches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.log")
+	tr := &rejectIDs{ids: map[string]bool{"b": true}}
+	a, err := NewDurableAsyncExporter(&Exporter{Transport: tr, BatchSize: 1}, path, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		if err := a.Add(Record{ID: id, Payload: "p"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a.Close()
+	if got := logIDs(t, path); got != "b" {
+		t.Fatalf("log holds %q, want only the failed batch", got)
+	}
+}
+
+func TestRecordLogTruncatesCorruptTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.log")
+	log, _, err := openRecordLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := log.append(Record{ID: id, Payload: "p"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	log.close()
+	valid, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tail := range map[string][]byte{
+		"torn length": {0, 0},
+		"torn body":   {0, 0, 0, 50, '{'},
+		"bad json":    {0, 0, 0, 3, 'x', 'y', 'z'},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := os.Truncate(path, valid.Size()); err != nil {
+				t.Fatal(err)
+			}
+			f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.Write(tail)
+			f.Close()
+
+			log, records, err := openRecordLog(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 2 || records[0].ID != "a" || records[1].ID != "b" {
+				t.Fatalf("recovered %v, want a and b", records)
+			}
+			if err := log.append(Record{ID: "c", Payload: "p"}); err != nil {
+				t.Fatal(err)
+			}
+			log.close()
+			if got := logIDs(t, path); got != "abc" {
+				t.Fatalf("log holds %q after append, want abc", got)
+			}
+		})
+	}
+}
+
+func TestDurableAsyncExporterRetriesAfterOutage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.log")
+	tr := &outageTransport{}
+	tr.down.Store(true)
+	failures := make(chan error, 100)
+	a, err := NewDurableAsyncExporter(&Exporter{Transport: tr}, path, 10*time.Millisecond, func(err error) { failures <- err })
+	if err != nil {
+		t.Fatal(err)
+	}
+	addAll(t, a, "ab")
+	// Let a few flushes fail while the endpoint is down.
+	for i := 0; i < 3; i++ {
+		select {
+		case <-failures:
+		case <-time.After(time.Second):
+			t.Fatal("no failed flush reported")
+		}
+	}
+	addAll(t, a, "c")
+	tr.down.Store(false)
+	// The records are retried by a later flush without a restart, still
+	// in the order they were added.
+	waitSent(t, &tr.InMemoryTransport, "abc")
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := logIDs(t, path); got != "" {
+		t.Fatalf("log holds %q after delivery, want empty", got)
+	}
+}
+
+func TestDurableAsyncExporterLogHoldsEachFailedRecordOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.log")
+	a, err := NewDurableAsyncExporter(&Exporter{Transport: rejectTransport{}, BatchSize: 1}, path, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each Add fills the batch and flushes every record so far, all of
+	// which fail again.
+	addAll(t, a, "abcd")
+	a.Close()
+	if got := logIDs(t, path); got != "abcd" {
+		t.Fatalf("log holds %q, want each undelivered record once", got)
+	}
+}
+
+func TestDurableAsyncExporterCoalescesRetriedRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.log")
+	tr := &outageTransport{}
+	tr.down.Store(true)
+	flushed := make(chan struct{}, 10)
+	a, err := NewDurableAsyncExporter(&Exporter{Transport: tr, BatchSize: 1}, path, 0, func(error) { flushed <- struct{}{} })
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.SetCoalescer(&Coalescer{Key: func(r Record) string { return r.ID }})
+	if err := a.Add(Record{ID: "k", Payload: "v1"}); err != nil {
+		t.Fatal(err)
+	}
+	<-flushed
+	tr.down.Store(false)
+	// The newer state of k replaces the failed one instead of following it.
+	if err := a.Add(Record{ID: "k", Payload: "v2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(tr.Batches) != 1 || len(tr.Batches[0]) != 1 || tr.Batches[0][0].Payload != "v2" {
+		t.Fatalf("delivered %v, want k once with v2", tr.Batches)
+	}
+}